@@ -0,0 +1,74 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/dannyroes/raytrace/bench"
+)
+
+func runBench(args []string) error {
+	var asJSON bool
+	var workers int
+	var count int
+
+	fs := newFlagSet("bench", "[scene...]")
+	fs.BoolVar(&asJSON, "json", false, "print results as JSON")
+	fs.IntVar(&workers, "workers", 0, "number of render workers (0 picks from the CPU count)")
+	fs.IntVar(&count, "count", 1, "render each scene this many times")
+
+	names, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+
+	scenes := bench.Scenes
+	if len(names) > 0 {
+		scenes = nil
+		for _, n := range names {
+			s, ok := bench.FindScene(n)
+			if !ok {
+				return fmt.Errorf("unknown scene %q, expected one of %s", n, strings.Join(benchSceneNames(), ", "))
+			}
+			scenes = append(scenes, s)
+		}
+	}
+
+	var results []bench.Result
+	for _, s := range scenes {
+		for i := 0; i < count; i++ {
+			if !asJSON {
+				fmt.Fprintf(os.Stderr, "Rendering %s (%d/%d)\n", s.Name, i+1, count)
+			}
+			results = append(results, bench.Run(s, workers))
+		}
+	}
+
+	if asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(results)
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "scene\tsize\tprimary\tsecondary\tshadow\trays/s\tallocs\talloc bytes\ttime\t")
+	for _, r := range results {
+		fmt.Fprintf(tw, "%s\t%dx%d\t%d\t%d\t%d\t%.0f\t%d\t%s\t%v\t\n",
+			r.Scene, r.Width, r.Height, r.PrimaryRays, r.SecondaryRays, r.ShadowRays,
+			r.RaysPerSecond, r.Allocs, formatBytes(r.AllocBytes), r.Duration.Truncate(time.Millisecond))
+	}
+
+	return tw.Flush()
+}
+
+func benchSceneNames() []string {
+	var names []string
+	for _, s := range bench.Scenes {
+		names = append(names, s.Name)
+	}
+	return names
+}
@@ -0,0 +1,68 @@
+package bench
+
+import (
+	"runtime"
+	"time"
+
+	"github.com/dannyroes/raytrace/world"
+)
+
+type Result struct {
+	Scene         string        `json:"scene"`
+	Width         int           `json:"width"`
+	Height        int           `json:"height"`
+	Workers       int           `json:"workers"`
+	PrimaryRays   uint64        `json:"primary_rays"`
+	SecondaryRays uint64        `json:"secondary_rays"`
+	ShadowRays    uint64        `json:"shadow_rays"`
+	RaysPerSecond float64       `json:"rays_per_second"`
+	Allocs        uint64        `json:"allocs"`
+	AllocBytes    uint64        `json:"alloc_bytes"`
+	Duration      time.Duration `json:"duration_ns"`
+}
+
+func (r Result) Rays() uint64 {
+	return r.PrimaryRays + r.SecondaryRays + r.ShadowRays
+}
+
+// Run renders the scene at its reference size and measures the render.
+// workers of 0 leaves the camera's default.
+func Run(s Scene, workers int) Result {
+	c, w := s.Build()
+	c.HSize = s.Width
+	c.VSize = s.Height
+	c.Supersample = 1
+	c.Workers = workers
+	c.CalcPixelSize()
+
+	w.Stats = &world.RayStats{}
+
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+
+	start := time.Now()
+	c.Render(w)
+	duration := time.Since(start)
+
+	runtime.ReadMemStats(&after)
+
+	res := Result{
+		Scene:         s.Name,
+		Width:         s.Width,
+		Height:        s.Height,
+		Workers:       workers,
+		PrimaryRays:   w.Stats.Primary,
+		SecondaryRays: w.Stats.Secondary,
+		ShadowRays:    w.Stats.Shadow,
+		Allocs:        after.Mallocs - before.Mallocs,
+		AllocBytes:    after.TotalAlloc - before.TotalAlloc,
+		Duration:      duration,
+	}
+
+	if duration > 0 {
+		res.RaysPerSecond = float64(res.Rays()) / duration.Seconds()
+	}
+
+	return res
+}
@@ -0,0 +1,60 @@
+package bench
+
+import "testing"
+
+func TestScenesBuild(t *testing.T) {
+	for _, s := range Scenes {
+		c, w := s.Build()
+		if c.Transform == nil {
+			t.Errorf("%s: scene has no camera transform", s.Name)
+		}
+
+		if len(w.Objects) == 0 || len(w.Lights) == 0 {
+			t.Errorf("%s: scene needs objects and lights, received %d and %d", s.Name, len(w.Objects), len(w.Lights))
+		}
+	}
+}
+
+func TestRun(t *testing.T) {
+	s, ok := FindScene("spheres")
+	if !ok {
+		t.Fatal("Expected to find the spheres scene")
+	}
+	s.Width = 8
+	s.Height = 6
+
+	res := Run(s, 1)
+
+	if res.PrimaryRays != 48 {
+		t.Errorf("Primary ray mismatch expected %d received %d", 48, res.PrimaryRays)
+	}
+
+	if res.ShadowRays == 0 {
+		t.Error("Expected shadow rays")
+	}
+
+	if res.Rays() != res.PrimaryRays+res.SecondaryRays+res.ShadowRays {
+		t.Errorf("Ray total mismatch received %d", res.Rays())
+	}
+
+	if res.Duration <= 0 || res.RaysPerSecond <= 0 {
+		t.Errorf("Expected timing to be recorded, received %+v", res)
+	}
+}
+
+func BenchmarkScenes(b *testing.B) {
+	for _, s := range Scenes {
+		s := s
+		b.Run(s.Name, func(b *testing.B) {
+			b.ReportAllocs()
+			rays := uint64(0)
+			seconds := 0.0
+			for i := 0; i < b.N; i++ {
+				res := Run(s, 0)
+				rays += res.Rays()
+				seconds += res.Duration.Seconds()
+			}
+			b.ReportMetric(float64(rays)/seconds, "rays/s")
+		})
+	}
+}
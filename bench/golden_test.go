@@ -0,0 +1,61 @@
+package bench
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dannyroes/raytrace/world"
+)
+
+// Run with -update after an intended change to the renderer's output to
+// rewrite the golden images.
+var update = flag.Bool("update", false, "rewrite the golden images in testdata/golden")
+
+// goldenMaxError allows for floating point differences between platforms
+// pushing a channel over a rounding boundary.
+const goldenMaxError = 2.0 / 255
+
+// TestGolden renders each reference scene at half its reference size and
+// compares it with the image stored in testdata/golden.
+func TestGolden(t *testing.T) {
+	for _, s := range Scenes {
+		c, w := s.Build()
+		c.HSize = s.Width / 2
+		c.VSize = s.Height / 2
+		c.Supersample = 1
+		c.CalcPixelSize()
+
+		image := c.Render(w).Quantize()
+		filename := filepath.Join("testdata", "golden", s.Name+".png")
+
+		if *update {
+			err := os.MkdirAll(filepath.Dir(filename), 0755)
+			if err == nil {
+				err = image.ToPNG(filename)
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+
+		expected, err := world.LoadPNG(filename)
+		if err != nil {
+			t.Errorf("%s: %v", s.Name, err)
+			continue
+		}
+
+		res, err := world.Compare(expected, image)
+		if err != nil {
+			t.Errorf("%s: %v", s.Name, err)
+			continue
+		}
+
+		if res.MaxError > goldenMaxError {
+			t.Errorf("%s: render differs from golden image, %d pixels differ, max error %.4f, psnr %.2f, ssim %.4f",
+				s.Name, res.Differing, res.MaxError, res.PSNR, res.SSIM)
+		}
+	}
+}
@@ -1,7 +1,6 @@
-package main
+package bench
 
 import (
-	"fmt"
 	"math"
 
 	"github.com/dannyroes/raytrace/data"
@@ -46,7 +45,9 @@ func hex(m material.MaterialType) shape.Shape {
 	return hex
 }
 
-func hexScene() {
+// Hex is three interlocking hexagons built from nested groups of spheres and
+// cylinders.
+func Hex() (*world.CameraType, world.WorldType) {
 	w := world.World()
 
 	w.Lights = append(w.Lights, world.PointLight(data.Point(2, 4, 2), material.Colour(1, 1, 1)))
@@ -72,10 +73,6 @@ func hexScene() {
 	w.Objects = []shape.Shape{
 		h1, h2, h3,
 	}
-	image := c.Render(w)
 
-	err := image.ToPNG("output/scene.png")
-	if err != nil {
-		fmt.Println(err)
-	}
+	return c, w
 }
@@ -0,0 +1,206 @@
+package bench
+
+import (
+	"bytes"
+	_ "embed"
+	"math"
+
+	"github.com/dannyroes/raytrace/data"
+	"github.com/dannyroes/raytrace/material"
+	"github.com/dannyroes/raytrace/shape"
+	"github.com/dannyroes/raytrace/world"
+)
+
+//go:embed teapot_lo.obj
+var teapotObj []byte
+
+// Scene is a reference scene rendered at a fixed size so that results can be
+// compared between builds.
+type Scene struct {
+	Name   string
+	Width  int
+	Height int
+	Build  func() (*world.CameraType, world.WorldType)
+}
+
+var Scenes = []Scene{
+	{"spheres", 32, 24, Spheres},
+	{"glass", 48, 36, Glass},
+	{"mesh", 16, 12, Mesh},
+	{"csg", 48, 36, Csg},
+	{"groups", 48, 36, Hex},
+}
+
+func FindScene(name string) (Scene, bool) {
+	for _, s := range Scenes {
+		if s.Name == name {
+			return s, true
+		}
+	}
+
+	return Scene{}, false
+}
+
+// Spheres is a grid of matte and shiny spheres on a plane.
+func Spheres() (*world.CameraType, world.WorldType) {
+	w := world.World()
+	w.Lights = append(w.Lights, world.PointLight(data.Point(-10, 10, -10), material.Colour(1, 1, 1)))
+
+	floor := shape.Plane()
+	m := material.Material()
+	m.Colour = material.Colour(0.9, 0.9, 0.85)
+	m.Specular = 0
+	floor.SetMaterial(m)
+	w.Objects = append(w.Objects, floor)
+
+	for x := 0; x < 7; x++ {
+		for z := 0; z < 7; z++ {
+			s := shape.Sphere()
+			s.SetTransform(data.Scaling(0.4, 0.4, 0.4).Translate(float64(x)-3, 0.4, float64(z)-3))
+
+			m := material.Material()
+			m.Colour = material.Colour(float64(x)/6, 0.4, float64(z)/6)
+			m.Shininess = 10 + float64(x*z*10)
+			s.SetMaterial(m)
+
+			w.Objects = append(w.Objects, s)
+		}
+	}
+
+	c := world.Camera(400, 300, math.Pi/3)
+	c.Transform = data.ViewTransform(data.Point(0, 5, -8), data.Point(0, 0, 0), data.Vector(0, 1, 0))
+
+	return c, w
+}
+
+// Glass is a pair of refractive spheres and a mirror ball in a checkered
+// room, so most pixels spawn reflection and refraction rays.
+func Glass() (*world.CameraType, world.WorldType) {
+	w := world.World()
+	w.Lights = append(w.Lights,
+		world.PointLight(data.Point(-4, 4, -4), material.Colour(0.8, 0.8, 0.8)),
+		world.PointLight(data.Point(4, 6, -2), material.Colour(0.3, 0.3, 0.3)),
+	)
+
+	floor := shape.Plane()
+	m := material.Material()
+	m.Pattern = material.CheckersPattern(material.Colour(1, 0.9, 0.9), material.Colour(0.2, 0.2, 0.2))
+	m.Specular = 0
+	m.Reflective = 0.4
+	floor.SetMaterial(m)
+
+	wall := shape.Plane()
+	wall.SetTransform(data.RotateX(math.Pi/2).Translate(0, 0, 5))
+	m = material.Material()
+	m.Pattern = material.StripePattern(material.Colour(0.9, 0.9, 1), material.Colour(0.1, 0.1, 0.6))
+	m.Specular = 0
+	wall.SetMaterial(m)
+
+	glass := shape.GlassSphere()
+	glass.SetTransform(data.Translation(-0.5, 1, 0))
+	m = glass.GetMaterial()
+	m.Colour = material.Colour(0.1, 0.1, 0.1)
+	m.Diffuse = 0.1
+	m.Reflective = 0.9
+	m.Transparency = 0.9
+	m.RefractiveIndex = 1.52
+	glass.SetMaterial(m)
+
+	bubble := shape.GlassSphere()
+	bubble.SetTransform(data.Scaling(0.5, 0.5, 0.5).Translate(-0.5, 1, 0))
+	m = bubble.GetMaterial()
+	m.Colour = material.Colour(0.1, 0.1, 0.1)
+	m.Diffuse = 0.1
+	m.Reflective = 0.9
+	m.Transparency = 0.9
+	m.RefractiveIndex = 1.00029
+	bubble.SetMaterial(m)
+
+	mirror := shape.Sphere()
+	mirror.SetTransform(data.Scaling(0.6, 0.6, 0.6).Translate(1.5, 0.6, 1))
+	m = material.Material()
+	m.Colour = material.Colour(0.2, 0.2, 0.2)
+	m.Reflective = 0.9
+	mirror.SetMaterial(m)
+
+	w.Objects = []shape.Shape{floor, wall, glass, bubble, mirror}
+
+	c := world.Camera(400, 300, math.Pi/3)
+	c.Transform = data.ViewTransform(data.Point(0, 1.5, -5), data.Point(0, 1, 0), data.Vector(0, 1, 0))
+
+	return c, w
+}
+
+// Mesh is the low resolution teapot on a plane.
+func Mesh() (*world.CameraType, world.WorldType) {
+	obj, err := shape.LoadObjReader("teapot_lo.obj", bytes.NewReader(teapotObj))
+	if err != nil {
+		panic(err)
+	}
+
+	teapot := obj.GetGroup()
+	m := material.Material()
+	m.Colour = material.Colour(0.8, 0.5, 0.3)
+	m.Shininess = 50
+	teapot.SetMaterial(m)
+	teapot.SetTransform(data.Scaling(0.1, 0.1, 0.1).RotateX(-math.Pi / 2))
+
+	c, w, err := world.Studio(teapot, 400, 300)
+	if err != nil {
+		panic(err)
+	}
+
+	return c, w
+}
+
+// Csg is a set of constructive solid geometry shapes: a cube with a sphere cut
+// out, a lens made from intersecting spheres and a union of cylinders.
+func Csg() (*world.CameraType, world.WorldType) {
+	w := world.World()
+	w.Lights = append(w.Lights, world.PointLight(data.Point(-5, 8, -6), material.Colour(1, 1, 1)))
+
+	floor := shape.Plane()
+	m := material.Material()
+	m.Colour = material.Colour(0.8, 0.8, 0.8)
+	m.Specular = 0
+	floor.SetMaterial(m)
+
+	colour := func(s shape.Shape, r, g, b float64) shape.Shape {
+		m := material.Material()
+		m.Colour = material.Colour(r, g, b)
+		s.SetMaterial(m)
+		return s
+	}
+
+	cutSphere := shape.Sphere()
+	cutSphere.SetTransform(data.Scaling(1.3, 1.3, 1.3))
+	dice := shape.Csg(shape.CsgDifference, colour(shape.Cube(), 0.9, 0.2, 0.2), colour(cutSphere, 0.9, 0.9, 0.2))
+	dice.SetTransform(data.RotateY(math.Pi/5).Translate(-2.2, 1, 0))
+
+	lensRight := shape.Sphere()
+	lensRight.SetTransform(data.Translation(0.8, 0, 0))
+	lensLeft := shape.Sphere()
+	lensLeft.SetTransform(data.Translation(-0.8, 0, 0))
+	lens := shape.Csg(shape.CsgIntersection, colour(lensLeft, 0.2, 0.6, 0.9), colour(lensRight, 0.2, 0.6, 0.9))
+	lens.SetTransform(data.RotateY(math.Pi/2).Translate(0, 1, 0))
+
+	upright := shape.Cylinder()
+	upright.Minimum = -1
+	upright.Maximum = 1
+	upright.Closed = true
+	upright.SetTransform(data.Scaling(0.3, 1, 0.3))
+	across := shape.Cylinder()
+	across.Minimum = -1
+	across.Maximum = 1
+	across.Closed = true
+	across.SetTransform(data.Scaling(0.3, 1, 0.3).RotateZ(math.Pi / 2))
+	cross := shape.Csg(shape.CsgUnion, colour(upright, 0.3, 0.8, 0.3), colour(across, 0.3, 0.8, 0.3))
+	cross.SetTransform(data.Translation(2.2, 1, 0))
+
+	w.Objects = []shape.Shape{floor, dice, lens, cross}
+
+	c := world.Camera(400, 300, math.Pi/3)
+	c.Transform = data.ViewTransform(data.Point(0, 3, -7), data.Point(0, 0.8, 0), data.Vector(0, 1, 0))
+
+	return c, w
+}
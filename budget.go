@@ -0,0 +1,33 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/dannyroes/raytrace/world"
+)
+
+// renderBudget renders the scene in about the given time, returning the best
+// image it reached along with its metadata. The result depends on how fast
+// the machine is, so it is never cached.
+func renderBudget(ctx context.Context, scene string, c *world.CameraType, w world.WorldType, budget time.Duration, quiet bool) (world.CanvasType, map[string]string, error) {
+	w.Stats = &world.RayStats{}
+	start := time.Now()
+
+	image, plan, err := c.RenderBudget(ctx, w, budget)
+	if err != nil && err != context.Canceled {
+		return image, nil, err
+	}
+
+	if !quiet {
+		fmt.Printf("Budget of %v: depth %d, supersample %d, about %v a pass\n", budget, plan.MaxDepth, plan.Supersample, plan.PassCost.Round(time.Millisecond))
+	}
+
+	c.MaxDepth = plan.MaxDepth
+	c.Supersample = plan.Supersample
+	meta := renderMetadata(scene, c, w, time.Since(start))
+	meta["Render-Budget"] = budget.String()
+
+	return image, meta, err
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"crypto/sha256"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/dannyroes/raytrace/cache"
+	"github.com/dannyroes/raytrace/world"
+)
+
+type cacheOptions struct {
+	dir     string
+	force   bool
+	maxSize int64
+	maxAge  time.Duration
+}
+
+func (o *cacheOptions) register(fs *flag.FlagSet) {
+	dir := ""
+	if userCache, err := os.UserCacheDir(); err == nil {
+		dir = filepath.Join(userCache, "raytrace")
+	}
+
+	fs.StringVar(&o.dir, "cache", dir, "directory for cached renders (empty disables the cache)")
+	fs.BoolVar(&o.force, "force", false, "render even if the cache has the image, replacing the cached copy")
+	fs.Int64Var(&o.maxSize, "cache-max-size", 1024, "largest size of the cache in MiB (0 for no limit)")
+	fs.DurationVar(&o.maxAge, "cache-max-age", 0, "remove cached renders unused for this long (0 keeps them)")
+}
+
+func (o *cacheOptions) cache() *cache.Cache {
+	if o.dir == "" {
+		return nil
+	}
+
+	return cache.New(o.dir, o.maxSize*1024*1024, o.maxAge)
+}
+
+// renderKey identifies the image a scene renders to with the camera's final
+// settings. The build version is included so a new renderer starts afresh.
+func renderKey(scene string, c *world.CameraType) (string, error) {
+	key, err := world.SceneKey(scene)
+	if err != nil {
+		return "", err
+	}
+
+	settings := fmt.Sprintf("%s\n%dx%d\nsupersample %d\ndepth %d\n%s", key, c.HSize, c.VSize, c.Supersample, c.MaxDepth, buildVersion())
+	if c.Adaptive != nil {
+		settings += "\nadaptive " + c.Adaptive.String()
+	}
+	if c.Sampler != nil {
+		settings += "\nsampler " + c.Sampler.String()
+	}
+	if c.Filter != nil {
+		settings += "\nfilter " + c.Filter.String()
+	}
+	if c.Region != nil {
+		settings += "\nregion " + c.Region.String()
+	}
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(settings))), nil
+}
+
+// renderCached returns the cached image for the scene if there is one,
+// otherwise it renders with render and stores the result. A render that
+// fails is not cached but its image is still returned with the error.
+func renderCached(scene string, c *world.CameraType, w world.WorldType, opts *cacheOptions, quiet bool, render func(w world.WorldType) (world.CanvasType, error)) (world.CanvasType, map[string]string, error) {
+	store := opts.cache()
+
+	var key string
+	if store != nil {
+		var err error
+		key, err = renderKey(scene, c)
+		if err != nil {
+			return world.CanvasType{}, nil, err
+		}
+
+		if !opts.force {
+			image, meta, ok, err := store.Get(key)
+			if err != nil && !quiet {
+				fmt.Fprintf(os.Stderr, "Ignoring unreadable cache entry: %v\n", err)
+			}
+			if ok {
+				if !quiet {
+					fmt.Printf("Using cached render %s\n", key[:12])
+				}
+				return image, meta, nil
+			}
+		}
+	}
+
+	w.Stats = &world.RayStats{}
+	start := time.Now()
+	image, err := render(w)
+	meta := renderMetadata(scene, c, w, time.Since(start))
+	if err != nil {
+		return image, meta, err
+	}
+
+	if store != nil {
+		meta["Cache-Key"] = key
+		err := store.Put(key, image, meta)
+		if err != nil && !quiet {
+			fmt.Fprintf(os.Stderr, "Could not cache render: %v\n", err)
+		}
+	}
+
+	return image, meta, nil
+}
@@ -0,0 +1,142 @@
+package cache
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dannyroes/raytrace/world"
+)
+
+// Cache stores finished renders as PNG files named by a key describing
+// everything that went into them. An entry's modification time is its last
+// use, which eviction works from.
+type Cache struct {
+	Dir string
+	// MaxSize is the total size in bytes to keep the cache under, or 0 for
+	// no limit. The least recently used entries are removed first.
+	MaxSize int64
+	// MaxAge removes entries not used for this long, or 0 for no limit.
+	MaxAge time.Duration
+}
+
+func New(dir string, maxSize int64, maxAge time.Duration) *Cache {
+	return &Cache{Dir: dir, MaxSize: maxSize, MaxAge: maxAge}
+}
+
+func (c *Cache) path(key string) string {
+	return filepath.Join(c.Dir, key+".png")
+}
+
+// Get returns the image stored under key along with its PNG metadata. The
+// boolean is false if there is no entry.
+func (c *Cache) Get(key string) (world.CanvasType, map[string]string, bool, error) {
+	filename := c.path(key)
+
+	image, err := world.LoadPNG(filename)
+	if os.IsNotExist(err) {
+		return world.CanvasType{}, nil, false, nil
+	}
+	if err != nil {
+		return world.CanvasType{}, nil, false, err
+	}
+
+	meta, err := world.ReadPNGMetadata(filename)
+	if err != nil {
+		return world.CanvasType{}, nil, false, err
+	}
+
+	now := time.Now()
+	os.Chtimes(filename, now, now)
+
+	return image, meta, true, nil
+}
+
+// Put stores the image under key and then evicts old entries. The file is
+// written beside its final name and renamed so readers never see half of it.
+func (c *Cache) Put(key string, image world.CanvasType, meta map[string]string) error {
+	err := os.MkdirAll(c.Dir, 0755)
+	if err != nil {
+		return err
+	}
+
+	tmp := c.path(key) + ".tmp"
+	err = image.ToPNGWithMetadata(tmp, meta)
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	err = os.Rename(tmp, c.path(key))
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	return c.Evict()
+}
+
+type entry struct {
+	path    string
+	size    int64
+	modTime time.Time
+}
+
+// Evict removes entries older than MaxAge, then the least recently used ones
+// until the cache is no larger than MaxSize.
+func (c *Cache) Evict() error {
+	files, err := os.ReadDir(c.Dir)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var entries []entry
+	var total int64
+	for _, f := range files {
+		if f.IsDir() || !strings.HasSuffix(f.Name(), ".png") {
+			continue
+		}
+
+		info, err := f.Info()
+		if err != nil {
+			continue
+		}
+
+		e := entry{filepath.Join(c.Dir, f.Name()), info.Size(), info.ModTime()}
+		if c.MaxAge > 0 && time.Since(e.modTime) > c.MaxAge {
+			err = os.Remove(e.path)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		entries = append(entries, e)
+		total += e.size
+	}
+
+	if c.MaxSize <= 0 {
+		return nil
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].modTime.Before(entries[j].modTime) })
+
+	for _, e := range entries {
+		if total <= c.MaxSize {
+			break
+		}
+
+		err = os.Remove(e.path)
+		if err != nil {
+			return err
+		}
+		total -= e.size
+	}
+
+	return nil
+}
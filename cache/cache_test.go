@@ -0,0 +1,114 @@
+package cache
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dannyroes/raytrace/material"
+	"github.com/dannyroes/raytrace/world"
+)
+
+func testImage() world.CanvasType {
+	c := world.Canvas(8, 6)
+	c.Fill(material.Colour(0.2, 0.6, 1))
+	return c
+}
+
+func TestGetMissing(t *testing.T) {
+	c := New(t.TempDir(), 0, 0)
+
+	_, _, ok, err := c.Get("missing")
+	if err != nil || ok {
+		t.Errorf("Expected a miss without error, received %v, %v", ok, err)
+	}
+}
+
+func TestPutGet(t *testing.T) {
+	c := New(t.TempDir(), 0, 0)
+
+	err := c.Put("abc", testImage(), map[string]string{"Scene": "scene.yml"})
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	image, meta, ok, err := c.Get("abc")
+	if err != nil || !ok {
+		t.Fatalf("Expected a hit, received %v, %v", ok, err)
+	}
+
+	res, err := world.Compare(testImage().Quantize(), image)
+	if err != nil || res.MaxError != 0 {
+		t.Errorf("Expected cached image to match, received %+v, %v", res, err)
+	}
+
+	if meta["Scene"] != "scene.yml" {
+		t.Errorf("Metadata mismatch expected %q received %q", "scene.yml", meta["Scene"])
+	}
+}
+
+func TestEvictAge(t *testing.T) {
+	dir := t.TempDir()
+	c := New(dir, 0, time.Hour)
+
+	for _, key := range []string{"old", "new"} {
+		err := c.Put(key, testImage(), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	old := time.Now().Add(-2 * time.Hour)
+	os.Chtimes(filepath.Join(dir, "old.png"), old, old)
+
+	err := c.Evict()
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	if _, _, ok, _ := c.Get("old"); ok {
+		t.Error("Expected old entry to be evicted")
+	}
+
+	if _, _, ok, _ := c.Get("new"); !ok {
+		t.Error("Expected new entry to be kept")
+	}
+}
+
+func TestEvictSize(t *testing.T) {
+	dir := t.TempDir()
+	c := New(dir, 0, 0)
+
+	keys := []string{"a", "b", "c"}
+	for i, key := range keys {
+		err := c.Put(key, testImage(), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		used := time.Now().Add(time.Duration(i-len(keys)) * time.Minute)
+		os.Chtimes(filepath.Join(dir, key+".png"), used, used)
+	}
+
+	info, err := os.Stat(filepath.Join(dir, "a.png"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c.MaxSize = info.Size() * 2
+	err = c.Evict()
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "a.png")); !os.IsNotExist(err) {
+		t.Error("Expected least recently used entry to be evicted")
+	}
+
+	for _, key := range []string{"b", "c"} {
+		if _, err := os.Stat(filepath.Join(dir, key+".png")); err != nil {
+			t.Errorf("Expected %s to be kept, received %v", key, err)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"crypto/sha256"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/dannyroes/raytrace/world"
+)
+
+type checkpointOptions struct {
+	file     string
+	interval time.Duration
+	resume   bool
+}
+
+func (o *checkpointOptions) register(fs *flag.FlagSet) {
+	fs.StringVar(&o.file, "checkpoint", "", "save finished tiles to this file so an interrupted render can be resumed")
+	fs.DurationVar(&o.interval, "checkpoint-interval", world.DefaultCheckpointInterval, "how often to save the checkpoint")
+	fs.BoolVar(&o.resume, "resume", false, "continue the render saved in -checkpoint")
+}
+
+// open returns the checkpoint for the render, or nil without -checkpoint. A
+// new render refuses to replace an existing checkpoint.
+func (o *checkpointOptions) open(scene string, c *world.CameraType, quiet bool) (*world.Checkpoint, error) {
+	if o.file == "" {
+		if o.resume {
+			return nil, errors.New("-resume needs -checkpoint")
+		}
+		return nil, nil
+	}
+
+	key, err := checkpointKey(scene, c)
+	if err != nil {
+		return nil, err
+	}
+
+	if o.resume {
+		cp, err := world.LoadCheckpoint(o.file, key, o.interval)
+		if err != nil {
+			return nil, err
+		}
+		if !quiet {
+			fmt.Printf("Resuming with %d tiles finished\n", cp.Finished())
+		}
+		return cp, nil
+	}
+
+	if _, err := os.Stat(o.file); err == nil {
+		return nil, fmt.Errorf("%s already exists, use -resume to continue it or remove it", o.file)
+	}
+
+	return world.NewCheckpoint(o.file, key, o.interval), nil
+}
+
+// checkpointKey extends renderKey with the tile layout, which has to match
+// for finished tiles to be reused.
+func checkpointKey(scene string, c *world.CameraType) (string, error) {
+	key, err := renderKey(scene, c)
+	if err != nil {
+		return "", err
+	}
+
+	settings := fmt.Sprintf("%s\ntiles %d %s", key, c.TileSize, c.TileOrder)
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(settings))), nil
+}
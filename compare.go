@@ -0,0 +1,206 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/dannyroes/raytrace/world"
+)
+
+type tolerance struct {
+	maxError float64
+	minPSNR  float64
+	minSSIM  float64
+}
+
+// check returns why the comparison falls outside the tolerance, or an empty
+// string if it does not.
+func (t tolerance) check(res world.Comparison) string {
+	var problems []string
+	if res.MaxError > t.maxError {
+		problems = append(problems, fmt.Sprintf("max error %.4f > %.4f", res.MaxError, t.maxError))
+	}
+	if res.PSNR < t.minPSNR {
+		problems = append(problems, fmt.Sprintf("psnr %.2f < %.2f", res.PSNR, t.minPSNR))
+	}
+	if res.SSIM < t.minSSIM || math.IsNaN(res.SSIM) {
+		problems = append(problems, fmt.Sprintf("ssim %.4f < %.4f", res.SSIM, t.minSSIM))
+	}
+
+	return strings.Join(problems, ", ")
+}
+
+func runCompare(args []string) error {
+	var diff string
+	var golden string
+	var update bool
+	var out string
+	var tol tolerance
+	var opts renderOptions
+
+	fs := newFlagSet("compare", "<a.png> <b.png> | -golden <dir> <scene-dir>")
+	fs.StringVar(&diff, "diff", "", "write a false colour diff image to this file")
+	fs.StringVar(&golden, "golden", "", "render the scenes in <scene-dir> and compare them with the images in this directory")
+	fs.BoolVar(&update, "update", false, "with -golden, write the renders as the new golden images")
+	fs.StringVar(&out, "o", "output/regress", "with -golden, directory for the renders and diffs of failing scenes")
+	fs.Float64Var(&tol.maxError, "max-error", 0.05, "largest allowed difference in any channel of any pixel")
+	fs.Float64Var(&tol.minPSNR, "min-psnr", 30, "smallest allowed PSNR in dB")
+	fs.Float64Var(&tol.minSSIM, "min-ssim", 0.98, "smallest allowed SSIM")
+	fs.IntVar(&opts.workers, "workers", 0, "with -golden, number of render workers (0 picks from the CPU count)")
+
+	files, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+
+	if golden != "" {
+		if len(files) != 1 {
+			fs.Usage()
+			return flag.ErrHelp
+		}
+
+		opts.depth = -1
+		opts.quiet = true
+		return runRegression(files[0], golden, out, update, tol, &opts)
+	}
+
+	if len(files) != 2 {
+		fs.Usage()
+		return flag.ErrHelp
+	}
+
+	a, err := world.LoadPNG(files[0])
+	if err != nil {
+		return err
+	}
+
+	b, err := world.LoadPNG(files[1])
+	if err != nil {
+		return err
+	}
+
+	res, err := world.Compare(a, b)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("pixels:    %d of %d differ\n", res.Differing, a.Width*a.Height)
+	fmt.Printf("max error: %.4f\n", res.MaxError)
+	fmt.Printf("rmse:      %.4f\n", res.RMSE)
+	fmt.Printf("psnr:      %s\n", formatPSNR(res.PSNR))
+	fmt.Printf("ssim:      %.4f\n", res.SSIM)
+
+	if diff != "" {
+		err = checkImageFormat(diff)
+		if err != nil {
+			return err
+		}
+
+		err = writeImage(res.Diff, diff, nil)
+		if err != nil {
+			return err
+		}
+	}
+
+	if problem := tol.check(res); problem != "" {
+		return errors.New(problem)
+	}
+
+	return nil
+}
+
+// runRegression renders every scene in dir and compares it with the image of
+// the same name in golden.
+func runRegression(dir, golden, out string, update bool, tol tolerance, opts *renderOptions) error {
+	scenes, err := filepath.Glob(filepath.Join(dir, "*.yml"))
+	if err != nil {
+		return err
+	}
+	sort.Strings(scenes)
+
+	if len(scenes) == 0 {
+		return fmt.Errorf("no scenes found in %s", dir)
+	}
+
+	failed := 0
+	for _, scene := range scenes {
+		name := strings.TrimSuffix(filepath.Base(scene), filepath.Ext(scene))
+		goldenFile := filepath.Join(golden, name+".png")
+
+		c, w, err := loadScene(scene, opts)
+		if err != nil {
+			fmt.Printf("FAIL %s: %v\n", name, err)
+			failed++
+			continue
+		}
+
+		image := c.Render(w).Quantize()
+
+		if update {
+			err = os.MkdirAll(golden, 0755)
+			if err == nil {
+				err = image.ToPNG(goldenFile)
+			}
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("UPDATE %s\n", name)
+			continue
+		}
+
+		expected, err := world.LoadPNG(goldenFile)
+		if err != nil {
+			fmt.Printf("FAIL %s: %v\n", name, err)
+			failed++
+			continue
+		}
+
+		res, err := world.Compare(expected, image)
+		if err != nil {
+			fmt.Printf("FAIL %s: %v\n", name, err)
+			failed++
+			continue
+		}
+
+		problem := tol.check(res)
+		if problem == "" {
+			fmt.Printf("PASS %s: max error %.4f, psnr %s, ssim %.4f\n", name, res.MaxError, formatPSNR(res.PSNR), res.SSIM)
+			continue
+		}
+
+		fmt.Printf("FAIL %s: %s\n", name, problem)
+		failed++
+
+		err = os.MkdirAll(out, 0755)
+		if err == nil {
+			err = image.ToPNG(filepath.Join(out, name+".png"))
+		}
+		if err == nil {
+			err = res.Diff.ToPNG(filepath.Join(out, name+"-diff.png"))
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d scene(s) failed", failed, len(scenes))
+	}
+
+	return nil
+}
+
+func formatPSNR(psnr float64) string {
+	if math.IsInf(psnr, 1) {
+		return "inf"
+	}
+
+	return fmt.Sprintf("%.2f dB", psnr)
+}
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/dannyroes/raytrace/data"
+	"github.com/dannyroes/raytrace/shape"
+	"github.com/dannyroes/raytrace/world"
+)
+
+type datasetInfo struct {
+	Scene      string            `json:"scene"`
+	Seed       int64             `json:"seed"`
+	Frames     int               `json:"frames"`
+	DepthScale float64           `json:"depth_scale"`
+	Distortion *world.Distortion `json:"distortion,omitempty"`
+	Objects    []datasetObject   `json:"objects"`
+}
+
+type datasetObject struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+type datasetFrame struct {
+	Image      string            `json:"image"`
+	Depth      string            `json:"depth"`
+	DepthScale float64           `json:"depth_scale"`
+	Instances  string            `json:"instances"`
+	Width      int               `json:"width"`
+	Height     int               `json:"height"`
+	Intrinsics data.Matrix       `json:"intrinsics"`
+	Extrinsics data.Matrix       `json:"extrinsics"`
+	Distortion *world.Distortion `json:"distortion,omitempty"`
+	From       [3]float64        `json:"from"`
+	To         [3]float64        `json:"to"`
+	Up         [3]float64        `json:"up"`
+}
+
+func runDataset(args []string) error {
+	var output string
+	var frames int
+	var seed int64
+	var spread float64
+	var posesFile string
+	var distortion string
+	var depthScale float64
+	var opts renderOptions
+
+	fs := newFlagSet("dataset", "<scene.yml>")
+	fs.StringVar(&output, "o", "output/dataset", "directory to write the frames to")
+	fs.IntVar(&frames, "frames", 10, "number of frames to render")
+	fs.Int64Var(&seed, "seed", 1, "seed for the random camera poses")
+	fs.Float64Var(&spread, "spread", 45, "largest change in camera angle for random poses, in degrees")
+	fs.StringVar(&posesFile, "poses", "", "YAML list of camera poses (from, to, up) to use instead of random ones")
+	fs.StringVar(&distortion, "distortion", "", "Brown-Conrady lens distortion as k1,k2,p1,p2,k3")
+	fs.Float64Var(&depthScale, "depth-scale", 0.001, "scene units per step of the 16 bit depth images")
+	fs.IntVar(&opts.width, "width", 0, "image width, overrides the scene camera")
+	fs.IntVar(&opts.height, "height", 0, "image height, overrides the scene camera")
+	fs.IntVar(&opts.supersample, "supersample", 0, "supersample factor, overrides the scene camera")
+	fs.IntVar(&opts.workers, "workers", 0, "number of render workers (0 picks from the CPU count)")
+	fs.IntVar(&opts.depth, "depth", -1, "maximum reflection/refraction depth (-1 keeps the default)")
+	fs.BoolVar(&opts.quiet, "q", false, "suppress progress output")
+
+	files, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+
+	if len(files) != 1 {
+		fs.Usage()
+		return flag.ErrHelp
+	}
+
+	if frames < 1 {
+		return fmt.Errorf("frames must be at least 1")
+	}
+
+	if depthScale <= 0 {
+		return fmt.Errorf("depth scale must be positive")
+	}
+
+	d, err := parseDistortion(distortion)
+	if err != nil {
+		return err
+	}
+
+	c, w, err := loadScene(files[0], &opts)
+	if err != nil {
+		return err
+	}
+	c.Observer = nil
+	c.Distortion = d
+
+	var poses []world.Pose
+	if posesFile != "" {
+		poses, err = world.LoadPoses(posesFile)
+		if err != nil {
+			return err
+		}
+		if frames < len(poses) {
+			poses = poses[:frames]
+		}
+	} else {
+		poses, err = world.RandomPoses(c, w, frames, seed, spread*math.Pi/180)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = os.MkdirAll(output, 0755)
+	if err != nil {
+		return err
+	}
+
+	info := datasetInfo{
+		Scene:      files[0],
+		Seed:       seed,
+		Frames:     len(poses),
+		DepthScale: depthScale,
+		Distortion: d,
+	}
+	for i, obj := range w.Objects {
+		info.Objects = append(info.Objects, datasetObject{i + 1, fmt.Sprintf("object %d (%s)", i+1, shape.Kind(obj))})
+	}
+
+	err = writeJSONFile(filepath.Join(output, "dataset.json"), info)
+	if err != nil {
+		return err
+	}
+
+	for i, pose := range poses {
+		if !opts.quiet {
+			fmt.Printf("Rendering frame %d/%d\n", i+1, len(poses))
+		}
+
+		c.Transform = pose.Transform()
+		name := fmt.Sprintf("frame_%04d", i)
+		frame := datasetFrame{
+			Image:      name + ".png",
+			Depth:      name + "_depth.png",
+			DepthScale: depthScale,
+			Instances:  name + "_instances.png",
+			Width:      c.HSize,
+			Height:     c.VSize,
+			Intrinsics: c.Intrinsics(),
+			Extrinsics: c.Extrinsics(),
+			Distortion: d,
+			From:       [3]float64{pose.From.X, pose.From.Y, pose.From.Z},
+			To:         [3]float64{pose.To.X, pose.To.Y, pose.To.Z},
+			Up:         [3]float64{pose.Up.X, pose.Up.Y, pose.Up.Z},
+		}
+
+		err = c.Render(w).ToPNG(filepath.Join(output, frame.Image))
+		if err != nil {
+			return err
+		}
+
+		gt := c.GroundTruth(w)
+		err = gt.ToDepthPNG(filepath.Join(output, frame.Depth), depthScale)
+		if err != nil {
+			return err
+		}
+
+		err = gt.ToInstancePNG(filepath.Join(output, frame.Instances))
+		if err != nil {
+			return err
+		}
+
+		err = writeJSONFile(filepath.Join(output, name+".json"), frame)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// parseDistortion reads up to five comma separated coefficients in the order
+// k1, k2, p1, p2, k3. Missing ones are zero.
+func parseDistortion(s string) (*world.Distortion, error) {
+	if s == "" {
+		return nil, nil
+	}
+
+	parts := strings.Split(s, ",")
+	if len(parts) > 5 {
+		return nil, fmt.Errorf("distortion takes at most 5 coefficients, received %d", len(parts))
+	}
+
+	var values [5]float64
+	for i, p := range parts {
+		v, err := strconv.ParseFloat(strings.TrimSpace(p), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid distortion coefficient %q", p)
+		}
+		values[i] = v
+	}
+
+	return &world.Distortion{K1: values[0], K2: values[1], P1: values[2], P2: values[3], K3: values[4]}, nil
+}
+
+func writeJSONFile(filename string, v interface{}) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+
+	enc := json.NewEncoder(f)
+	enc.SetIndent("", "  ")
+	err = enc.Encode(v)
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
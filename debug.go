@@ -0,0 +1,66 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+)
+
+func runDebugPixel(args []string) error {
+	var output string
+	var opts renderOptions
+
+	fs := newFlagSet("debug-pixel", "<scene.yml> <x> <y>")
+	fs.StringVar(&output, "o", "", "write the ray tree to this file instead of stdout")
+	fs.IntVar(&opts.width, "width", 0, "image width, overrides the scene camera")
+	fs.IntVar(&opts.height, "height", 0, "image height, overrides the scene camera")
+	fs.IntVar(&opts.depth, "depth", -1, "maximum reflection/refraction depth (-1 keeps the default)")
+
+	files, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+
+	if len(files) != 3 {
+		fs.Usage()
+		return flag.ErrHelp
+	}
+
+	x, err := strconv.Atoi(files[1])
+	if err != nil {
+		return fmt.Errorf("invalid x %q", files[1])
+	}
+
+	y, err := strconv.Atoi(files[2])
+	if err != nil {
+		return fmt.Errorf("invalid y %q", files[2])
+	}
+
+	opts.quiet = true
+	c, w, err := loadScene(files[0], &opts)
+	if err != nil {
+		return err
+	}
+
+	trace, err := c.TracePixel(w, x, y)
+	if err != nil {
+		return err
+	}
+
+	var out io.Writer = os.Stdout
+	if output != "" {
+		f, err := os.Create(output)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		out = f
+	}
+
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(trace)
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/dannyroes/raytrace/farm"
+	"github.com/dannyroes/raytrace/world"
+)
+
+// renderFarm renders the scene on the workers, sending each the scene, the
+// files it loads and the camera settings from the command line.
+func renderFarm(ctx context.Context, scene string, c *world.CameraType, w world.WorldType, workers []string, opts *renderOptions) (world.CanvasType, error) {
+	settings := farm.Settings{
+		Width:       c.HSize,
+		Height:      c.VSize,
+		Supersample: c.Supersample,
+		MaxDepth:    c.MaxDepth,
+		Adaptive:    c.Adaptive,
+		Filter:      c.Filter,
+		Sampler:     opts.sampler,
+		SamplerSeed: opts.seed,
+	}
+
+	job, err := farm.NewJob(scene, settings)
+	if err != nil {
+		return world.CanvasType{}, err
+	}
+
+	co := &farm.Coordinator{Workers: workers, Job: job}
+	return co.Render(ctx, c, w.Stats)
+}
+
+func runWorker(args []string) error {
+	var listen string
+	var workers int
+	var maxTiles int
+	var quiet bool
+
+	fs := newFlagSet("worker", "")
+	fs.StringVar(&listen, "listen", "localhost:8080", "address to serve tiles on; workers have no authentication, so only listen where trusted hosts alone can connect")
+	fs.IntVar(&workers, "workers", 0, "number of goroutines each tile is rendered on (0 picks from the CPU count)")
+	fs.IntVar(&maxTiles, "max-tiles", farm.DefaultMaxTiles, "most tiles to render at once, others are refused until one finishes")
+	fs.BoolVar(&quiet, "q", false, "don't log jobs and tiles")
+
+	files, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+
+	if len(files) != 0 {
+		fs.Usage()
+		return flag.ErrHelp
+	}
+
+	wk := farm.NewWorker(workers)
+	wk.MaxTiles = maxTiles
+	if !quiet {
+		wk.Log = os.Stdout
+	}
+
+	fmt.Printf("Serving tiles on %s\n", listen)
+	return http.ListenAndServe(listen, wk)
+}
@@ -0,0 +1,334 @@
+package farm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dannyroes/raytrace/material"
+	"github.com/dannyroes/raytrace/world"
+)
+
+// DefaultTileSize is the side of the tiles a Coordinator hands out when
+// TileSize is not set. They are larger than a local render's so each
+// request is worth its round trip and workers can split them across cores.
+const DefaultTileSize = 64
+
+// DefaultTileTimeout is how long a worker has to render a tile when
+// TileTimeout is not set.
+const DefaultTileTimeout = 10 * time.Minute
+
+// DefaultMaxFailures is how many times in a row a worker can fail before it
+// is dropped when MaxFailures is not set.
+const DefaultMaxFailures = 3
+
+// busyWait is how long to wait before asking a busy worker for a tile again.
+const busyWait = time.Second
+
+// errUnknownJob is returned for a tile of a job the worker doesn't have.
+var errUnknownJob = errors.New("worker does not have the job")
+
+// errWorkerBusy is returned for a tile a worker has no room to render.
+var errWorkerBusy = errors.New("worker is busy")
+
+// Coordinator renders a job on a set of workers. Each worker is sent the job
+// and then one tile at a time until every tile is done. A tile that fails or
+// takes longer than TileTimeout goes back in the queue for another worker,
+// and a worker that fails MaxFailures times in a row is dropped. Once there
+// is nothing left to hand out, idle workers also take copies of tiles still
+// running elsewhere, so one slow machine doesn't hold up the end of the
+// render; whichever copy finishes first is used and the other is cancelled.
+type Coordinator struct {
+	// Workers are the base URLs of the workers, such as http://host:8080.
+	Workers []string
+	Job     Job
+	// TileSize is capped at MaxTileSize, the largest tile workers render.
+	TileSize    int
+	TileTimeout time.Duration
+	MaxFailures int
+	// Client is used for every request, http.DefaultClient if nil.
+	Client *http.Client
+}
+
+func (co *Coordinator) tileSize() int {
+	if co.TileSize > MaxTileSize {
+		return MaxTileSize
+	}
+	if co.TileSize > 0 {
+		return co.TileSize
+	}
+
+	return DefaultTileSize
+}
+
+func (co *Coordinator) tileTimeout() time.Duration {
+	if co.TileTimeout > 0 {
+		return co.TileTimeout
+	}
+
+	return DefaultTileTimeout
+}
+
+func (co *Coordinator) maxFailures() int {
+	if co.MaxFailures > 0 {
+		return co.MaxFailures
+	}
+
+	return DefaultMaxFailures
+}
+
+func (co *Coordinator) client() *http.Client {
+	if co.Client != nil {
+		return co.Client
+	}
+
+	return http.DefaultClient
+}
+
+// tileResult is a finished tile and the rays a worker traced for it.
+type tileResult struct {
+	result world.TileResult
+	stats  world.RayStats
+}
+
+// Render renders the image the camera describes, its Region if it has one,
+// and returns it once every tile is back. The camera's size and settings
+// must match the job's. Tiles are handed out in the camera's TileOrder and
+// reported to its Observer as they arrive, and the rays traced by the
+// workers are added to stats if it is set.
+//
+// If ctx is cancelled, or every worker is dropped, the image so far is
+// returned with the error.
+func (co *Coordinator) Render(ctx context.Context, c *world.CameraType, stats *world.RayStats) (world.CanvasType, error) {
+	width, height := c.ImageSize()
+	image := world.Canvas(width, height)
+
+	if len(co.Workers) == 0 {
+		return image, errors.New("no workers to render on")
+	}
+
+	id, err := co.Job.ID()
+	if err != nil {
+		return image, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	tiles := world.Tiles(width, height, co.tileSize(), c.TileOrder)
+	s := newSchedule(ctx, len(tiles), len(co.Workers))
+	results := make(chan tileResult, len(co.Workers))
+
+	start := time.Now()
+	if c.Observer != nil {
+		c.Observer.RenderStarted(world.RenderStarted{Width: width, Height: height, Supersample: c.Supersample, Tiles: len(tiles), Workers: len(co.Workers)})
+	}
+
+	ox, oy := 0, 0
+	if c.Region != nil {
+		ox, oy = c.Region.X, c.Region.Y
+	}
+
+	wg := &sync.WaitGroup{}
+	for _, url := range co.Workers {
+		wg.Add(1)
+		go func(url string) {
+			defer wg.Done()
+			co.work(ctx, strings.TrimSuffix(url, "/"), id, tiles, ox, oy, s, results)
+		}(url)
+	}
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	done := 0
+	lastUpdate := start
+	var total world.RayStats
+
+	for r := range results {
+		image.WriteTile(r.result)
+		done += len(r.result.Pixels)
+		total.Primary += r.stats.Primary
+		total.Secondary += r.stats.Secondary
+		total.Shadow += r.stats.Shadow
+
+		if c.Observer == nil {
+			continue
+		}
+
+		c.Observer.TileFinished(world.TileFinished{Tile: r.result.Tile, Pixels: r.result.Pixels})
+
+		if time.Since(lastUpdate) > progressInterval(c) {
+			lastUpdate = time.Now()
+			elapsed := time.Since(start)
+			c.Observer.RenderProgress(world.RenderProgress{
+				Pixels:      done,
+				TotalPixels: width * height,
+				Percent:     float64(done) / float64(width*height) * 100,
+				Elapsed:     elapsed,
+				Remaining:   time.Duration(float64(elapsed) * float64(width*height-done) / float64(done)),
+			})
+		}
+	}
+
+	err = s.err()
+	if stats != nil {
+		stats.Primary += total.Primary
+		stats.Secondary += total.Secondary
+		stats.Shadow += total.Shadow
+	}
+	if c.Observer != nil {
+		c.Observer.RenderFinished(world.RenderFinished{Pixels: done, Duration: time.Since(start), Stats: total, Err: err})
+	}
+
+	return image, err
+}
+
+func progressInterval(c *world.CameraType) time.Duration {
+	if c.ProgressInterval > 0 {
+		return c.ProgressInterval
+	}
+
+	return world.DefaultProgressInterval
+}
+
+// work sends tiles to one worker until there are none left or the worker
+// fails too many times in a row. Tiles are offset by ox, oy to place them in
+// the full frame.
+func (co *Coordinator) work(ctx context.Context, url, id string, tiles []world.Tile, ox, oy int, s *schedule, results chan<- tileResult) {
+	failures := 0
+	loaded := false
+	var err error
+
+	for failures < co.maxFailures() {
+		if !loaded {
+			err = co.sendJob(ctx, url)
+			if err != nil {
+				failures++
+				if !wait(ctx, time.Duration(failures)*100*time.Millisecond) {
+					break
+				}
+				continue
+			}
+			loaded = true
+		}
+
+		a := s.next(co.tileTimeout())
+		if a == nil {
+			return
+		}
+
+		tile := tiles[a.tile]
+		frame := world.Tile{X: tile.X + ox, Y: tile.Y + oy, Width: tile.Width, Height: tile.Height}
+
+		var res TileResponse
+		res, err = co.renderTile(a.ctx, url, id, frame)
+		if err == nil && len(res.Pixels) != tile.Width*tile.Height*3 {
+			err = fmt.Errorf("expected %d pixels for tile %+v, received %d", tile.Width*tile.Height, frame, len(res.Pixels)/3)
+		}
+		if err != nil {
+			// Losing the race to another copy of the tile isn't a failure.
+			if s.failed(a) {
+				continue
+			}
+			// A worker busy with other renders isn't failing, so try it
+			// again later.
+			if err == errWorkerBusy {
+				if !wait(ctx, busyWait) {
+					break
+				}
+				continue
+			}
+			// The worker may have restarted, so send the job again.
+			if err == errUnknownJob {
+				loaded = false
+			}
+
+			failures++
+			if !wait(ctx, time.Duration(failures)*100*time.Millisecond) {
+				break
+			}
+			continue
+		}
+		failures = 0
+
+		if !s.finished(a) {
+			continue
+		}
+
+		pixels := make([]material.ColourTuple, tile.Width*tile.Height)
+		for i := range pixels {
+			pixels[i] = material.Colour(res.Pixels[i*3], res.Pixels[i*3+1], res.Pixels[i*3+2])
+		}
+
+		results <- tileResult{world.TileResult{Tile: tile, Pixels: pixels}, res.Stats}
+	}
+
+	s.lost(err)
+}
+
+// wait sleeps for d, returning false if ctx is cancelled first.
+func wait(ctx context.Context, d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-t.C:
+		return true
+	}
+}
+
+func (co *Coordinator) sendJob(ctx context.Context, url string) error {
+	var res JobResponse
+	return co.post(ctx, url+"/jobs", co.Job, &res)
+}
+
+func (co *Coordinator) renderTile(ctx context.Context, url, id string, tile world.Tile) (TileResponse, error) {
+	var res TileResponse
+	err := co.post(ctx, url+"/tiles", TileRequest{Job: id, Tile: tile}, &res)
+	return res, err
+}
+
+// post sends req as JSON and decodes the JSON reply into res.
+func (co *Coordinator) post(ctx context.Context, url string, req, res interface{}) error {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+
+	r, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	r.Header.Set("Content-Type", "application/json")
+
+	resp, err := co.client().Do(r)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound && strings.HasSuffix(url, "/tiles") {
+		return errUnknownJob
+	}
+	if resp.StatusCode == http.StatusServiceUnavailable && strings.HasSuffix(url, "/tiles") {
+		return errWorkerBusy
+	}
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("%s: %s: %s", url, resp.Status, strings.TrimSpace(string(msg)))
+	}
+
+	return json.NewDecoder(resp.Body).Decode(res)
+}
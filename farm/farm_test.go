@@ -0,0 +1,362 @@
+package farm
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dannyroes/raytrace/material"
+	"github.com/dannyroes/raytrace/world"
+)
+
+const testObj = `v -1 1 0
+v -1 0 0
+v 1 0 0
+v 1 1 0
+
+f 1 2 3
+f 1 3 4
+`
+
+const testScene = `- add: camera
+  width: 20
+  height: 10
+  field-of-view: 0.785
+  from: [0, 1.5, -5]
+  to: [0, 1, 0]
+  up: [0, 1, 0]
+
+- add: light
+  at: [-10, 10, -10]
+  intensity: [1, 1, 1]
+
+- add: sphere
+  material:
+    reflective: 0.5
+
+- add: obj
+  file: ../models/square.obj
+  material:
+    colour: [1, 0, 0]
+`
+
+// writeTestScene writes a scene loading a model from a sibling directory,
+// returning the scene's path.
+func writeTestScene(t *testing.T) string {
+	dir := t.TempDir()
+
+	for _, d := range []string{"scenes", "models"} {
+		err := os.Mkdir(filepath.Join(dir, d), 0755)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err := os.WriteFile(filepath.Join(dir, "models", "square.obj"), []byte(testObj), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	scene := filepath.Join(dir, "scenes", "scene.yml")
+	err = os.WriteFile(scene, []byte(testScene), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return scene
+}
+
+var testSettings = Settings{Width: 20, Height: 10, Supersample: 2, MaxDepth: world.MaxReflect, Sampler: "halton", SamplerSeed: 3}
+
+// localRender renders the scene on this machine with the settings.
+func localRender(t *testing.T, scene string, settings Settings) (*world.CameraType, world.CanvasType) {
+	c, w, err := world.LoadScene(scene)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	err = settings.Apply(c)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	return c, c.Render(w)
+}
+
+func startWorkers(t *testing.T, n int) []string {
+	var urls []string
+	for i := 0; i < n; i++ {
+		server := httptest.NewServer(NewWorker(1))
+		t.Cleanup(server.Close)
+		urls = append(urls, server.URL)
+	}
+
+	return urls
+}
+
+func expectImage(t *testing.T, expected, image world.CanvasType) {
+	t.Helper()
+
+	if image.Width != expected.Width || image.Height != expected.Height {
+		t.Fatalf("Size mismatch expected %dx%d received %dx%d", expected.Width, expected.Height, image.Width, image.Height)
+	}
+
+	for x := 0; x < expected.Width; x++ {
+		for y := 0; y < expected.Height; y++ {
+			if !material.ColourEqual(image.Pixel(x, y), expected.Pixel(x, y)) {
+				t.Fatalf("Pixel %d,%d mismatch expected %+v received %+v", x, y, expected.Pixel(x, y), image.Pixel(x, y))
+			}
+		}
+	}
+}
+
+func TestNewJob(t *testing.T) {
+	job, err := NewJob(writeTestScene(t), testSettings)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	if job.Scene != "scenes/scene.yml" || len(job.Files) != 2 || job.Files[1].Name != "models/square.obj" {
+		t.Errorf("Expected the scene and model named from their shared directory, received %s and %+v", job.Scene, job.Files)
+	}
+}
+
+func TestJobRejectsEscapingNames(t *testing.T) {
+	for _, name := range []string{"../scene.yml", "/etc/scene.yml", "a/../../scene.yml", ""} {
+		job := Job{Scene: "scene.yml", Files: []File{{Name: name, Data: []byte(testScene)}}, Settings: testSettings}
+
+		_, _, err := job.load(t.TempDir())
+		if err == nil {
+			t.Errorf("%q: expected error", name)
+		}
+	}
+}
+
+func TestWorkerLimitsRequestSize(t *testing.T) {
+	job, err := NewJob(writeTestScene(t), testSettings)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	worker := NewWorker(1)
+	worker.MaxJobSize = 100
+	server := httptest.NewServer(worker)
+	defer server.Close()
+
+	co := &Coordinator{Client: server.Client(), Job: job}
+	err = co.sendJob(context.Background(), server.URL)
+	if err == nil || !strings.Contains(err.Error(), "too large") {
+		t.Errorf("Expected error sending a job over the limit, received %v", err)
+	}
+
+	worker.MaxJobSize = 0
+	err = co.sendJob(context.Background(), server.URL)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	resp, err := server.Client().Post(server.URL+"/tiles", "application/json", strings.NewReader(strings.Repeat(" ", maxTileRequestSize+1)))
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected a tile request over the limit to be refused, received %s", resp.Status)
+	}
+}
+
+func TestWorkerLimitsTiles(t *testing.T) {
+	job, err := NewJob(writeTestScene(t), testSettings)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	id, err := job.ID()
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	worker := NewWorker(1)
+	worker.MaxTiles = 1
+	server := httptest.NewServer(worker)
+	defer server.Close()
+
+	co := &Coordinator{Client: server.Client(), Job: job}
+	err = co.sendJob(context.Background(), server.URL)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	for _, tile := range []world.Tile{{X: 15, Y: 0, Width: 10, Height: 5}, {X: -1, Y: 0, Width: 2, Height: 2}, {X: 0, Y: 0, Width: 0, Height: 5}} {
+		_, err = co.renderTile(context.Background(), server.URL, id, tile)
+		if err == nil || !strings.Contains(err.Error(), "400") {
+			t.Errorf("Expected tile %+v outside the frame to be refused, received %v", tile, err)
+		}
+	}
+
+	if !worker.acquire() {
+		t.Fatal("Expected a free slot")
+	}
+	_, err = co.renderTile(context.Background(), server.URL, id, world.Tile{Width: 2, Height: 2})
+	if err != errWorkerBusy {
+		t.Errorf("Expected a busy worker to refuse the tile, received %v", err)
+	}
+
+	worker.release()
+	_, err = co.renderTile(context.Background(), server.URL, id, world.Tile{Width: 2, Height: 2})
+	if err != nil {
+		t.Errorf("Unexpected error %v", err)
+	}
+}
+
+func TestCoordinatorCapsTileSize(t *testing.T) {
+	co := &Coordinator{TileSize: MaxTileSize * 4}
+	if co.tileSize() != MaxTileSize {
+		t.Errorf("Expected tile size %d, received %d", MaxTileSize, co.tileSize())
+	}
+}
+
+func TestCoordinatorMatchesLocalRender(t *testing.T) {
+	scene := writeTestScene(t)
+	c, expected := localRender(t, scene, testSettings)
+
+	job, err := NewJob(scene, testSettings)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	stats := &world.RayStats{}
+	co := &Coordinator{Workers: startWorkers(t, 3), Job: job, TileSize: 4}
+	image, err := co.Render(context.Background(), c, stats)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	expectImage(t, expected, image)
+
+	if stats.Primary != 20*10*4 {
+		t.Errorf("Expected %d primary rays, received %d", 20*10*4, stats.Primary)
+	}
+}
+
+func TestCoordinatorRegionWithFilter(t *testing.T) {
+	scene := writeTestScene(t)
+	settings := testSettings
+	settings.Filter = &world.Filter{Kind: world.FilterMitchell}
+	c, full := localRender(t, scene, settings)
+
+	job, err := NewJob(scene, settings)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	c.Region = &world.Region{X: 5, Y: 2, Width: 9, Height: 7}
+	co := &Coordinator{Workers: startWorkers(t, 2), Job: job, TileSize: 4}
+	image, err := co.Render(context.Background(), c, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	expected := world.Canvas(9, 7)
+	expected.Draw(full, -5, -2)
+	expectImage(t, expected, image)
+}
+
+func TestCoordinatorRequeuesFromDeadAndSlowWorkers(t *testing.T) {
+	scene := writeTestScene(t)
+	c, expected := localRender(t, scene, testSettings)
+
+	job, err := NewJob(scene, testSettings)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	dead := httptest.NewServer(NewWorker(1))
+	dead.Close()
+
+	// Fails every other tile.
+	var requests int32
+	flakyWorker := NewWorker(1)
+	flaky := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/tiles" && atomic.AddInt32(&requests, 1)%2 == 0 {
+			http.Error(rw, "out of memory", http.StatusInternalServerError)
+			return
+		}
+		flakyWorker.ServeHTTP(rw, r)
+	}))
+	defer flaky.Close()
+
+	// Never finishes a tile.
+	stuck := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/tiles" {
+			io.Copy(io.Discard, r.Body)
+			<-r.Context().Done()
+			return
+		}
+		NewWorker(1).ServeHTTP(rw, r)
+	}))
+	defer stuck.Close()
+
+	urls := append(startWorkers(t, 1), dead.URL, flaky.URL, stuck.URL)
+	co := &Coordinator{Workers: urls, Job: job, TileSize: 4, TileTimeout: 500 * time.Millisecond}
+
+	image, err := co.Render(context.Background(), c, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	expectImage(t, expected, image)
+}
+
+func TestCoordinatorResendsJobToRestartedWorker(t *testing.T) {
+	scene := writeTestScene(t)
+	c, expected := localRender(t, scene, testSettings)
+
+	job, err := NewJob(scene, testSettings)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	// A new worker every few tiles, as if the process restarted.
+	var tiles int32
+	var worker atomic.Value
+	worker.Store(NewWorker(1))
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/tiles" && atomic.AddInt32(&tiles, 1)%3 == 0 {
+			worker.Store(NewWorker(1))
+		}
+		worker.Load().(*Worker).ServeHTTP(rw, r)
+	}))
+	defer server.Close()
+
+	co := &Coordinator{Workers: []string{server.URL}, Job: job, TileSize: 4}
+	image, err := co.Render(context.Background(), c, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	expectImage(t, expected, image)
+}
+
+func TestCoordinatorAllWorkersFail(t *testing.T) {
+	scene := writeTestScene(t)
+	c, _ := localRender(t, scene, testSettings)
+
+	job, err := NewJob(scene, testSettings)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	job.Files[0].Data = []byte("- add: [")
+
+	co := &Coordinator{Workers: startWorkers(t, 2), Job: job, TileSize: 4}
+	_, err = co.Render(context.Background(), c, nil)
+	if err == nil || !strings.Contains(err.Error(), "tiles unfinished") {
+		t.Errorf("Expected error once every worker failed, received %v", err)
+	}
+}
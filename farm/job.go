@@ -0,0 +1,175 @@
+// Package farm renders a scene across several machines. A Coordinator splits
+// the frame into tiles and hands them over HTTP to Workers, each of which
+// loads the scene from files the coordinator sends it and returns the pixels
+// of the tiles it renders as JSON.
+package farm
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/dannyroes/raytrace/sampler"
+	"github.com/dannyroes/raytrace/world"
+)
+
+// Settings are the camera options a render can change from those in its
+// scene, applied by each worker after loading it.
+type Settings struct {
+	Width       int
+	Height      int
+	Supersample int
+	MaxDepth    int
+	Adaptive    *world.AdaptiveSampling `json:",omitempty"`
+	Filter      *world.Filter           `json:",omitempty"`
+	// Sampler is the name of the sampler kind to use, if any.
+	Sampler     string `json:",omitempty"`
+	SamplerSeed int64  `json:",omitempty"`
+}
+
+// Apply sets up the camera with the settings.
+func (s Settings) Apply(c *world.CameraType) error {
+	if s.Width <= 0 || s.Height <= 0 {
+		return fmt.Errorf("invalid image size %dx%d", s.Width, s.Height)
+	}
+
+	c.HSize = s.Width
+	c.VSize = s.Height
+	c.CalcPixelSize()
+	c.Supersample = s.Supersample
+	c.MaxDepth = s.MaxDepth
+	c.Adaptive = s.Adaptive
+	c.Filter = s.Filter
+	c.Sampler = nil
+
+	if s.Sampler != "" {
+		kind, err := sampler.ParseKind(s.Sampler)
+		if err != nil {
+			return err
+		}
+		samples := c.Supersample * c.Supersample
+		if c.Adaptive != nil {
+			samples = c.Adaptive.MaxSamples
+		}
+		c.Sampler = sampler.New(kind, samples, s.SamplerSeed)
+	}
+
+	return nil
+}
+
+// File is a scene or one of the files it loads, named with slashes relative
+// to the directory holding all of a job's files.
+type File struct {
+	Name string
+	Data []byte
+}
+
+// Job is everything a worker needs to render tiles of a frame.
+type Job struct {
+	// Scene is the name of the scene among Files.
+	Scene    string
+	Files    []File
+	Settings Settings
+}
+
+// NewJob reads the scene along with every file it loads, see
+// world.SceneFiles. Files are named by their place relative to each other,
+// so a scene loading ../models/teapot.obj finds it on the worker too.
+func NewJob(scene string, settings Settings) (Job, error) {
+	paths, err := world.SceneFiles(scene)
+	if err != nil {
+		return Job{}, err
+	}
+
+	for i, p := range paths {
+		paths[i], err = filepath.Abs(p)
+		if err != nil {
+			return Job{}, err
+		}
+	}
+
+	root := filepath.Dir(paths[0])
+	for _, p := range paths[1:] {
+		for !strings.HasPrefix(p, root+string(filepath.Separator)) && filepath.Dir(root) != root {
+			root = filepath.Dir(root)
+		}
+	}
+
+	job := Job{Settings: settings}
+	for _, p := range paths {
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return Job{}, err
+		}
+
+		name, err := filepath.Rel(root, p)
+		if err != nil {
+			return Job{}, err
+		}
+
+		job.Files = append(job.Files, File{Name: filepath.ToSlash(name), Data: data})
+	}
+	job.Scene = job.Files[0].Name
+
+	return job, nil
+}
+
+// ID identifies the job by its contents, so a worker asked to load the same
+// job twice can keep the copy it has.
+func (j Job) ID() (string, error) {
+	data, err := json.Marshal(j)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%x", sha256.Sum256(data)), nil
+}
+
+// load writes the job's files under dir and loads the scene from there with
+// its settings applied.
+func (j Job) load(dir string) (*world.CameraType, world.WorldType, error) {
+	for _, f := range j.Files {
+		name, err := localName(f.Name)
+		if err != nil {
+			return nil, world.WorldType{}, err
+		}
+
+		filename := filepath.Join(dir, name)
+		err = os.MkdirAll(filepath.Dir(filename), 0755)
+		if err == nil {
+			err = os.WriteFile(filename, f.Data, 0644)
+		}
+		if err != nil {
+			return nil, world.WorldType{}, err
+		}
+	}
+
+	name, err := localName(j.Scene)
+	if err != nil {
+		return nil, world.WorldType{}, err
+	}
+
+	c, w, err := world.LoadScene(filepath.Join(dir, name))
+	if err != nil {
+		return c, w, err
+	}
+	if c.Transform == nil {
+		return c, w, fmt.Errorf("%s has no camera", j.Scene)
+	}
+
+	return c, w, j.Settings.Apply(c)
+}
+
+// localName turns a file name from a job into a relative path, refusing any
+// that would land outside the directory the job is written to.
+func localName(name string) (string, error) {
+	p := filepath.Clean(filepath.FromSlash(name))
+	if name == "" || filepath.IsAbs(p) || p == ".." || strings.HasPrefix(p, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("invalid file name %q in job", name)
+	}
+
+	return p, nil
+}
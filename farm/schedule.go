@@ -0,0 +1,192 @@
+package farm
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// attempt is one worker's try at a tile, cancelled if another worker
+// finishes the tile first.
+type attempt struct {
+	tile    int
+	started time.Time
+	ctx     context.Context
+	cancel  context.CancelFunc
+}
+
+// schedule hands out the tiles of a render to workers, keeping track of the
+// tiles waiting, the attempts running and the tiles done.
+type schedule struct {
+	ctx       context.Context
+	mu        sync.Mutex
+	cond      *sync.Cond
+	tiles     int
+	pending   []int
+	running   map[int][]*attempt
+	done      []bool
+	remaining int
+	workers   int
+	lastErr   error
+}
+
+func newSchedule(ctx context.Context, tiles, workers int) *schedule {
+	s := &schedule{
+		ctx:       ctx,
+		tiles:     tiles,
+		running:   map[int][]*attempt{},
+		done:      make([]bool, tiles),
+		remaining: tiles,
+		workers:   workers,
+	}
+	s.cond = sync.NewCond(&s.mu)
+
+	for i := 0; i < tiles; i++ {
+		s.pending = append(s.pending, i)
+	}
+
+	// Wake anyone waiting for a tile once the render is cancelled.
+	go func() {
+		<-ctx.Done()
+		s.mu.Lock()
+		s.cond.Broadcast()
+		s.mu.Unlock()
+	}()
+
+	return s
+}
+
+// next waits for a tile to render, returning nil once there are none left
+// or the render is cancelled. Waiting tiles come first; after that the tile
+// that has been running longest on a single worker is tried again. The
+// attempt's context expires after timeout.
+func (s *schedule) next(timeout time.Duration) *attempt {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for {
+		if s.ctx.Err() != nil || s.remaining == 0 {
+			return nil
+		}
+
+		tile := -1
+		if len(s.pending) > 0 {
+			tile = s.pending[0]
+			s.pending = s.pending[1:]
+		} else {
+			tile = s.straggler()
+		}
+
+		if tile >= 0 {
+			a := &attempt{tile: tile, started: time.Now()}
+			a.ctx, a.cancel = context.WithTimeout(s.ctx, timeout)
+			s.running[tile] = append(s.running[tile], a)
+			return a
+		}
+
+		s.cond.Wait()
+	}
+}
+
+// straggler returns the running tile that started longest ago among those
+// with only one attempt, or -1 if there isn't one.
+func (s *schedule) straggler() int {
+	tile := -1
+	var started time.Time
+
+	for i, attempts := range s.running {
+		if len(attempts) != 1 {
+			continue
+		}
+		if tile < 0 || attempts[0].started.Before(started) {
+			tile, started = i, attempts[0].started
+		}
+	}
+
+	return tile
+}
+
+func (s *schedule) remove(a *attempt) {
+	a.cancel()
+
+	attempts := s.running[a.tile]
+	for i := range attempts {
+		if attempts[i] == a {
+			s.running[a.tile] = append(attempts[:i], attempts[i+1:]...)
+			break
+		}
+	}
+	if len(s.running[a.tile]) == 0 {
+		delete(s.running, a.tile)
+	}
+}
+
+// finished records the attempt's tile as done, cancelling any other attempts
+// at it. It returns false if another attempt got there first.
+func (s *schedule) finished(a *attempt) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.remove(a)
+	if s.done[a.tile] {
+		return false
+	}
+
+	s.done[a.tile] = true
+	s.remaining--
+	for _, other := range s.running[a.tile] {
+		other.cancel()
+	}
+	delete(s.running, a.tile)
+
+	s.cond.Broadcast()
+	return true
+}
+
+// failed gives up on the attempt, putting its tile back at the front of the
+// queue unless it is still running elsewhere. It returns true if the tile
+// was already done, in which case the failure doesn't count against the
+// worker.
+func (s *schedule) failed(a *attempt) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.remove(a)
+	if s.done[a.tile] {
+		return true
+	}
+
+	if len(s.running[a.tile]) == 0 {
+		s.pending = append([]int{a.tile}, s.pending...)
+	}
+
+	s.cond.Broadcast()
+	return false
+}
+
+// lost drops a worker that failed too often.
+func (s *schedule) lost(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.workers--
+	s.lastErr = err
+	s.cond.Broadcast()
+}
+
+// err explains why the render stopped short, or returns nil if every tile
+// is done.
+func (s *schedule) err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.remaining == 0 {
+		return nil
+	}
+	if s.ctx.Err() != nil {
+		return s.ctx.Err()
+	}
+
+	return fmt.Errorf("%d of %d tiles unfinished after every worker failed, the last with %v", s.remaining, s.tiles, s.lastErr)
+}
@@ -0,0 +1,284 @@
+package farm
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+
+	"github.com/dannyroes/raytrace/world"
+)
+
+// maxJobs is how many loaded scenes a worker keeps, dropping the oldest.
+const maxJobs = 4
+
+// DefaultMaxJobSize is the largest /jobs request body, scene and models
+// included, a worker accepts when MaxJobSize is not set.
+const DefaultMaxJobSize = 64 << 20
+
+// maxTileRequestSize is the largest /tiles request body a worker accepts.
+const maxTileRequestSize = 64 << 10
+
+// MaxTileSize is the largest width and height of a tile a worker renders.
+const MaxTileSize = 256
+
+// DefaultMaxTiles is how many tiles a worker renders at once when MaxTiles
+// is not set. Each tile is already split across every core, so more would
+// only queue for them.
+const DefaultMaxTiles = 2
+
+// JobResponse is what a worker sends back after loading a job.
+type JobResponse struct {
+	ID string
+}
+
+// TileRequest asks a worker for the pixels of a tile of a loaded job. The
+// tile is in the full frame, not the coordinator's region.
+type TileRequest struct {
+	Job  string
+	Tile world.Tile
+}
+
+// TileResponse carries the red, green and blue of each pixel of a tile, row
+// by row, along with the rays the worker traced for it.
+type TileResponse struct {
+	Pixels []float64
+	Stats  world.RayStats
+}
+
+// Worker serves the HTTP side of a render farm:
+//
+//	POST /jobs   takes a Job and replies with a JobResponse once it is loaded
+//	POST /tiles  takes a TileRequest and replies with a TileResponse
+//
+// A tile of a job the worker doesn't have, because it restarted or dropped
+// the job for newer ones, gets a 404 and the coordinator sends the job again.
+//
+// There is no authentication: anyone who can reach a worker can have it
+// load and render any scene, so it must only be reachable from trusted
+// hosts. Request bodies are limited to MaxJobSize for jobs and 64KiB for
+// tiles, tiles must lie in the frame and be at most MaxTileSize on a side,
+// and a tile asked for while MaxTiles others are rendering gets a 503.
+type Worker struct {
+	// Workers is the number of goroutines each tile is rendered on, 0 picks
+	// from the CPU count.
+	Workers int
+	// MaxJobSize is the largest job in bytes, DefaultMaxJobSize if 0.
+	MaxJobSize int64
+	// MaxTiles is the most tiles rendered at once, DefaultMaxTiles if 0.
+	MaxTiles int
+	// Log, if set, gets a line for each job loaded and tile rendered.
+	Log io.Writer
+
+	mu        sync.Mutex
+	jobs      map[string]*loadedJob
+	order     []string
+	rendering chan struct{}
+}
+
+type loadedJob struct {
+	c *world.CameraType
+	w world.WorldType
+}
+
+func NewWorker(workers int) *Worker {
+	return &Worker{Workers: workers}
+}
+
+func (wk *Worker) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(rw, "expected POST", http.StatusMethodNotAllowed)
+		return
+	}
+
+	switch r.URL.Path {
+	case "/jobs":
+		wk.serveJob(rw, r)
+	case "/tiles":
+		wk.serveTile(rw, r)
+	default:
+		http.NotFound(rw, r)
+	}
+}
+
+func (wk *Worker) serveJob(rw http.ResponseWriter, r *http.Request) {
+	var job Job
+	err := readJSON(rw, r, wk.maxJobSize(), &job)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	id, err := job.ID()
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if wk.job(id) == nil {
+		dir, err := os.MkdirTemp("", "raytrace-job-")
+		if err != nil {
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		defer os.RemoveAll(dir)
+
+		c, w, err := job.load(dir)
+		if err != nil {
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+		c.Workers = wk.Workers
+
+		wk.add(id, &loadedJob{c: c, w: w})
+		wk.logf("Loaded %s as job %s\n", job.Scene, id[:12])
+	}
+
+	writeJSON(rw, JobResponse{ID: id})
+}
+
+func (wk *Worker) serveTile(rw http.ResponseWriter, r *http.Request) {
+	var req TileRequest
+	err := readJSON(rw, r, maxTileRequestSize, &req)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	job := wk.job(req.Job)
+	if job == nil {
+		http.Error(rw, fmt.Sprintf("unknown job %q", req.Job), http.StatusNotFound)
+		return
+	}
+
+	region := world.Region{X: req.Tile.X, Y: req.Tile.Y, Width: req.Tile.Width, Height: req.Tile.Height}
+	err = region.Validate(job.c.HSize, job.c.VSize)
+	if err == nil && (region.Width > MaxTileSize || region.Height > MaxTileSize) {
+		err = fmt.Errorf("tile %v is larger than %dx%d", region, MaxTileSize, MaxTileSize)
+	}
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if !wk.acquire() {
+		http.Error(rw, "worker is busy", http.StatusServiceUnavailable)
+		return
+	}
+	defer wk.release()
+
+	// Each tile is rendered as a region of the frame, so it comes out the
+	// same as that part of a render on one machine.
+	c := *job.c
+	c.Region = &region
+	w := job.w
+	w.Stats = &world.RayStats{}
+
+	image, err := c.RenderContext(r.Context(), w)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	res := TileResponse{Pixels: make([]float64, 0, image.Width*image.Height*3), Stats: *w.Stats}
+	for y := 0; y < image.Height; y++ {
+		for x := 0; x < image.Width; x++ {
+			colour := image.Pixel(x, y)
+			res.Pixels = append(res.Pixels, colour.Red(), colour.Green(), colour.Blue())
+		}
+	}
+
+	writeJSON(rw, res)
+	wk.logf("Rendered tile %d,%d %dx%d of job %s\n", req.Tile.X, req.Tile.Y, req.Tile.Width, req.Tile.Height, req.Job[:12])
+}
+
+func (wk *Worker) maxJobSize() int64 {
+	if wk.MaxJobSize > 0 {
+		return wk.MaxJobSize
+	}
+
+	return DefaultMaxJobSize
+}
+
+// acquire takes one of the MaxTiles slots for rendering a tile, returning
+// false if they are all in use.
+func (wk *Worker) acquire() bool {
+	wk.mu.Lock()
+	if wk.rendering == nil {
+		n := wk.MaxTiles
+		if n <= 0 {
+			n = DefaultMaxTiles
+		}
+		wk.rendering = make(chan struct{}, n)
+	}
+	rendering := wk.rendering
+	wk.mu.Unlock()
+
+	select {
+	case rendering <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+func (wk *Worker) release() {
+	<-wk.rendering
+}
+
+func (wk *Worker) job(id string) *loadedJob {
+	wk.mu.Lock()
+	defer wk.mu.Unlock()
+
+	return wk.jobs[id]
+}
+
+func (wk *Worker) add(id string, job *loadedJob) {
+	wk.mu.Lock()
+	defer wk.mu.Unlock()
+
+	if wk.jobs == nil {
+		wk.jobs = map[string]*loadedJob{}
+	}
+	if _, exists := wk.jobs[id]; exists {
+		return
+	}
+
+	wk.jobs[id] = job
+	wk.order = append(wk.order, id)
+	if len(wk.order) > maxJobs {
+		delete(wk.jobs, wk.order[0])
+		wk.order = wk.order[1:]
+	}
+}
+
+func (wk *Worker) logf(format string, args ...interface{}) {
+	if wk.Log != nil {
+		fmt.Fprintf(wk.Log, format, args...)
+	}
+}
+
+// readJSON decodes the request body, of at most limit bytes, into v. The
+// body is read to the end so the server notices if the coordinator hangs
+// up, cancelling the request's context and the render with it.
+func readJSON(rw http.ResponseWriter, r *http.Request, limit int64, v interface{}) error {
+	data, err := io.ReadAll(http.MaxBytesReader(rw, r.Body, limit))
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(data, v)
+}
+
+func writeJSON(rw http.ResponseWriter, v interface{}) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	rw.Header().Set("Content-Type", "application/json")
+	rw.Write(data)
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"math"
+
+	"github.com/dannyroes/raytrace/material"
+	"github.com/dannyroes/raytrace/shape"
+	"github.com/dannyroes/raytrace/world"
+)
+
+const galleryGap = 8
+
+func runGallery(args []string) error {
+	var opts renderOptions
+	var columns int
+
+	fs := newFlagSet("gallery", "<scene.yml>")
+	opts.register(fs, "output/materials.png")
+	fs.IntVar(&columns, "columns", 0, "swatches per row (0 makes the sheet roughly square)")
+
+	files, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+
+	if len(files) != 1 || columns < 0 {
+		fs.Usage()
+		return flag.ErrHelp
+	}
+
+	err = checkImageFormat(opts.output)
+	if err != nil {
+		return err
+	}
+
+	materials, err := world.SceneMaterials(files[0])
+	if err != nil {
+		return err
+	}
+
+	if len(materials) == 0 {
+		return errors.New("scene defines no materials")
+	}
+
+	tileWidth, tileHeight := 160, 120
+	if opts.width > 0 {
+		tileWidth = opts.width
+	}
+	if opts.height > 0 {
+		tileHeight = opts.height
+	}
+
+	if columns == 0 {
+		columns = int(math.Ceil(math.Sqrt(float64(len(materials)))))
+	}
+	rows := (len(materials) + columns - 1) / columns
+	labelHeight := glyphHeight + 6
+
+	sheet := world.Canvas(columns*(tileWidth+galleryGap)+galleryGap, rows*(tileHeight+labelHeight+galleryGap)+galleryGap)
+	sheet.Fill(material.Colour(0.15, 0.15, 0.15))
+
+	for i, m := range materials {
+		if !opts.quiet {
+			fmt.Printf("Rendering %s (%d/%d)\n", m.Name, i+1, len(materials))
+		}
+
+		swatch, err := renderSwatch(m.Material, tileWidth, tileHeight, &opts)
+		if err != nil {
+			return fmt.Errorf("%s: %v", m.Name, err)
+		}
+
+		x := galleryGap + (i%columns)*(tileWidth+galleryGap)
+		y := galleryGap + (i/columns)*(tileHeight+labelHeight+galleryGap)
+		sheet.Draw(swatch, x, y)
+		drawText(sheet, x, y+tileHeight+3, fitText(m.Name, tileWidth), material.White)
+	}
+
+	return writeImage(sheet, opts.output, nil)
+}
+
+func renderSwatch(m material.MaterialType, width, height int, opts *renderOptions) (world.CanvasType, error) {
+	s := shape.Sphere()
+	s.SetMaterial(m)
+
+	c, w, err := world.Studio(s, width, height)
+	if err != nil {
+		return world.CanvasType{}, err
+	}
+
+	err = opts.apply(c)
+	if err != nil {
+		return world.CanvasType{}, err
+	}
+	c.Observer = nil
+
+	return c.Render(w), nil
+}
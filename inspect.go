@@ -0,0 +1,195 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/dannyroes/raytrace/data"
+	"github.com/dannyroes/raytrace/material"
+	"github.com/dannyroes/raytrace/shape"
+	"github.com/dannyroes/raytrace/world"
+)
+
+func runInspect(args []string) error {
+	var maxDepth int
+	var summary bool
+
+	fs := newFlagSet("inspect", "<scene.yml>")
+	fs.IntVar(&maxDepth, "depth", -1, "deepest level of the object tree to print (-1 prints everything)")
+	fs.BoolVar(&summary, "summary", false, "only print the totals")
+
+	files, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+
+	if len(files) != 1 {
+		fs.Usage()
+		return flag.ErrHelp
+	}
+
+	c, w, err := world.LoadScene(files[0])
+	if err != nil {
+		return err
+	}
+
+	if !summary {
+		printObjectTree(os.Stdout, w, maxDepth)
+		fmt.Println()
+	}
+	printSceneStats(os.Stdout, c, w)
+
+	return nil
+}
+
+func printObjectTree(out io.Writer, w world.WorldType, maxDepth int) {
+	for _, obj := range w.Objects {
+		hidden := 0
+
+		shape.Walk(obj, func(s shape.Shape, depth int) {
+			if maxDepth >= 0 && depth > maxDepth {
+				hidden++
+				return
+			}
+
+			indent := strings.Repeat("  ", depth)
+			fmt.Fprintf(out, "%s%s\n", indent, describeShape(s))
+			fmt.Fprintf(out, "%s  transform: %s\n", indent, formatMatrix(s.GetTransform()))
+			switch s.(type) {
+			case *shape.GroupType, *shape.CsgType:
+			default:
+				fmt.Fprintf(out, "%s  material: %s\n", indent, formatMaterial(s.GetMaterial()))
+			}
+			fmt.Fprintf(out, "%s  bounds: %s\n", indent, formatBounds(s.Bounds()))
+		})
+
+		if hidden > 0 {
+			fmt.Fprintf(out, "%s(%d nested shapes not shown)\n", strings.Repeat("  ", maxDepth+1), hidden)
+		}
+	}
+}
+
+func printSceneStats(out io.Writer, c *world.CameraType, w world.WorldType) {
+	stats := world.Inspect(w)
+
+	if c.Transform != nil {
+		fmt.Fprintf(out, "Camera:     %dx%d, field of view %.3f, supersample %d\n", c.HSize, c.VSize, c.FieldOfView, c.Supersample)
+	} else {
+		fmt.Fprintf(out, "Camera:     none\n")
+	}
+	fmt.Fprintf(out, "Objects:    %d\n", stats.Objects)
+	fmt.Fprintf(out, "Primitives: %d\n", stats.Primitives)
+	fmt.Fprintf(out, "Triangles:  %d\n", stats.Triangles)
+	fmt.Fprintf(out, "Groups:     %d\n", stats.Groups)
+	fmt.Fprintf(out, "CSG:        %d\n", stats.Csgs)
+	fmt.Fprintf(out, "Lights:     %d\n", stats.Lights)
+	fmt.Fprintf(out, "Memory:     ~%s\n", formatBytes(stats.Memory))
+	fmt.Fprintf(out, "Bounds:     %s\n", formatBounds(stats.Bounds))
+}
+
+func describeShape(s shape.Shape) string {
+	desc := shape.Kind(s)
+
+	switch v := s.(type) {
+	case *shape.GroupType:
+		desc += fmt.Sprintf(" (%d children)", len(v.Children))
+	case *shape.CsgType:
+		desc += fmt.Sprintf(" (%s)", v.Operation())
+	case *shape.CylinderType:
+		desc += fmt.Sprintf(" (min %g, max %g, closed %t)", v.Minimum, v.Maximum, v.Closed)
+	case *shape.ConeType:
+		desc += fmt.Sprintf(" (min %g, max %g, closed %t)", v.Minimum, v.Maximum, v.Closed)
+	}
+
+	if !s.CastsShadow() {
+		desc += " [no shadow]"
+	}
+
+	return desc
+}
+
+func formatMatrix(m data.Matrix) string {
+	if m == nil {
+		return "none"
+	}
+
+	if m.Equals(data.IdentityMatrix()) {
+		return "identity"
+	}
+
+	rows := make([]string, len(m))
+	for i, row := range m {
+		rows[i] = fmt.Sprintf("%.4g %.4g %.4g %.4g", row[0], row[1], row[2], row[3])
+	}
+
+	return "[" + strings.Join(rows, "; ") + "]"
+}
+
+func formatTuple(t data.Tuple) string {
+	return fmt.Sprintf("(%.4g, %.4g, %.4g)", t.X, t.Y, t.Z)
+}
+
+func formatBounds(b shape.Bounds) string {
+	if b.IsEmpty() {
+		return "empty"
+	}
+
+	return formatTuple(b.Min) + " to " + formatTuple(b.Max)
+}
+
+func formatMaterial(m material.MaterialType) string {
+	var parts []string
+
+	if m.Pattern != nil {
+		parts = append(parts, "pattern "+patternName(m.Pattern))
+	} else {
+		parts = append(parts, fmt.Sprintf("colour (%.3g, %.3g, %.3g)", m.Colour.Red(), m.Colour.Green(), m.Colour.Blue()))
+	}
+
+	parts = append(parts,
+		fmt.Sprintf("ambient %.3g", m.Ambient),
+		fmt.Sprintf("diffuse %.3g", m.Diffuse),
+		fmt.Sprintf("specular %.3g", m.Specular),
+		fmt.Sprintf("shininess %.3g", m.Shininess),
+	)
+
+	if m.Reflective > 0 {
+		parts = append(parts, fmt.Sprintf("reflective %.3g", m.Reflective))
+	}
+	if m.Transparency > 0 {
+		parts = append(parts, fmt.Sprintf("transparency %.3g", m.Transparency), fmt.Sprintf("refractive-index %.3g", m.RefractiveIndex))
+	}
+
+	return strings.Join(parts, ", ")
+}
+
+func patternName(p material.Pattern) string {
+	switch p.(type) {
+	case *material.StripePatternType:
+		return "stripe"
+	case *material.GradientPatternType:
+		return "gradient"
+	case *material.RingPatternType:
+		return "ring"
+	case *material.CheckersPatternType:
+		return "checkers"
+	}
+
+	return "unknown"
+}
+
+func formatBytes(b uint64) string {
+	units := []string{"B", "KiB", "MiB", "GiB"}
+	size := float64(b)
+
+	i := 0
+	for size >= 1024 && i < len(units)-1 {
+		size /= 1024
+		i++
+	}
+
+	return fmt.Sprintf("%.1f %s", size, units[i])
+}
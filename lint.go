@@ -0,0 +1,48 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/dannyroes/raytrace/world"
+)
+
+func runLint(args []string) error {
+	var strict bool
+
+	fs := newFlagSet("lint", "<scene.yml>...")
+	fs.BoolVar(&strict, "strict", false, "treat warnings as failures")
+
+	files, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+
+	if len(files) == 0 {
+		fs.Usage()
+		return flag.ErrHelp
+	}
+
+	failed := 0
+	for _, f := range files {
+		diags, err := world.LintScene(f)
+		if err != nil {
+			fmt.Printf("%s: %v\n", f, err)
+			failed++
+			continue
+		}
+
+		for _, d := range diags {
+			fmt.Printf("%s: %s\n", f, d)
+			if strict || d.Severity == world.SeverityError {
+				failed++
+			}
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d problem(s) found", failed)
+	}
+
+	return nil
+}
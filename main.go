@@ -1,11 +1,11 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"os"
 
 	"github.com/dannyroes/raytrace/data"
-	"github.com/dannyroes/raytrace/world"
 )
 
 type Environment struct {
@@ -19,50 +19,108 @@ type Projectile struct {
 }
 
 func main() {
-	hexScene()
-	return
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
 
-	filename := "scene.yml"
+	cmd := findCommand(os.Args[1])
+	if cmd == nil {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n", os.Args[1])
+		usage()
+		os.Exit(2)
+	}
 
-	if len(os.Args) == 2 {
-		filename = os.Args[1]
+	err := cmd.run(os.Args[2:])
+	if err == flag.ErrHelp {
+		os.Exit(2)
 	}
 
-	drawFromYaml(filename)
-	// width := 250
-	// height := 125
-	// supersample := 1
-
-	// fmt.Println(os.Args)
-
-	// if len(os.Args) == 3 {
-	// 	w, err := strconv.Atoi(os.Args[1])
-	// 	if err == nil {
-	// 		h, err := strconv.Atoi(os.Args[2])
-	// 		if err == nil {
-	// 			width = w
-	// 			height = h
-	// 		}
-	// 	}
-	// }
-
-	// drawScene(width, height, supersample)
-	// fmt.Println("Done!")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", cmd.name, err)
+		os.Exit(1)
+	}
 }
 
-func drawFromYaml(f string) {
-	c, w, err := world.LoadScene(f)
-	if err != nil {
-		fmt.Println(err)
-		return
+type command struct {
+	name    string
+	args    string
+	summary string
+	run     func(args []string) error
+}
+
+var commands []command
+
+func init() {
+	commands = []command{
+		{"render", "<scene.yml>", "render a YAML scene to an image", runRender},
+		{"bench", "[scene...]", "render the reference scenes and report ray throughput", runBench},
+		{"compare", "<a.png> <b.png>", "compare two images, or renders against golden images", runCompare},
+		{"dataset", "<scene.yml>", "render frames with camera parameters, depth and instance masks", runDataset},
+		{"debug-pixel", "<scene.yml> <x> <y>", "trace one pixel and print every ray it spawns as JSON", runDebugPixel},
+		{"gallery", "<scene.yml>", "render every material defined in a scene onto one sheet", runGallery},
+		{"inspect", "<scene.yml>", "print the object tree and totals for a scene", runInspect},
+		{"lint", "<scene.yml>...", "check scenes for common mistakes", runLint},
+		{"metadata", "<image.png>...", "print the render settings stored in PNG images", runMetadata},
+		{"view", "<model.obj>", "render an OBJ model in a ready-made studio scene", runView},
+		{"watch", "<scene.yml>", "re-render a scene whenever it or its files change", runWatch},
+		{"worker", "", "render tiles for other machines running render -farm", runWorker},
 	}
+}
 
-	c.Verbose = true
-	image := c.Render(w)
-	err = image.ToPNG("output/scene.png")
-	if err != nil {
-		fmt.Println(err)
+func findCommand(name string) *command {
+	for i := range commands {
+		if commands[i].name == name {
+			return &commands[i]
+		}
+	}
+
+	return nil
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: raytrace <command> [arguments]\n\nCommands:\n")
+	width := 0
+	for _, c := range commands {
+		if len(c.name) > width {
+			width = len(c.name)
+		}
+	}
+	for _, c := range commands {
+		fmt.Fprintf(os.Stderr, "  %-*s %s\n", width, c.name, c.summary)
+	}
+	fmt.Fprintf(os.Stderr, "\nRun 'raytrace <command> -h' for command options.\n")
+}
+
+// parseFlags allows flags to appear before or after positional arguments,
+// so both "render -o out.png scene.yml" and "render scene.yml -o out.png" work.
+func parseFlags(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+
+	for {
+		err := fs.Parse(args)
+		if err != nil {
+			return nil, err
+		}
+
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+func newFlagSet(name, args string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: raytrace %s %s [options]\n\nOptions:\n", name, args)
+		fs.PrintDefaults()
 	}
+
+	return fs
 }
 
 // func drawScene(width, height, supersample int) {
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"crypto/sha256"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dannyroes/raytrace/world"
+)
+
+// version is set at build time with -ldflags "-X main.version=...". When it
+// is empty the module version recorded by the go tool is used.
+var version = ""
+
+func buildVersion() string {
+	if version != "" {
+		return version
+	}
+
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" {
+		return info.Main.Version
+	}
+
+	return "unknown"
+}
+
+// renderMetadata describes how an image was rendered so it can be stored in
+// the PNG. w.Stats should have been set before rendering for the ray counts.
+func renderMetadata(scene string, c *world.CameraType, w world.WorldType, duration time.Duration) map[string]string {
+	meta := map[string]string{
+		"Software":         "raytrace " + buildVersion(),
+		"Creation Time":    time.Now().UTC().Format(time.RFC3339),
+		"Scene":            scene,
+		"Camera-Size":      fmt.Sprintf("%dx%d", c.HSize, c.VSize),
+		"Field-Of-View":    strconv.FormatFloat(c.FieldOfView, 'g', 6, 64),
+		"Camera-Transform": formatMatrix(c.Transform),
+		"Supersample":      strconv.Itoa(c.Supersample),
+		"Max-Depth":        strconv.Itoa(c.MaxDepth),
+		"Render-Time":      duration.String(),
+	}
+
+	if c.Adaptive != nil {
+		meta["Adaptive-Sampling"] = c.Adaptive.String()
+	}
+
+	if c.Sampler != nil {
+		meta["Sampler"] = c.Sampler.String()
+	}
+
+	if c.Filter != nil {
+		meta["Filter"] = c.Filter.String()
+	}
+
+	if c.Region != nil {
+		meta["Region"] = c.Region.String()
+	}
+
+	if hash, err := sourceHash(scene); err == nil {
+		meta["Scene-SHA256"] = hash
+	}
+
+	if w.Stats != nil {
+		meta["Primary-Rays"] = strconv.FormatUint(w.Stats.Primary, 10)
+		meta["Secondary-Rays"] = strconv.FormatUint(w.Stats.Secondary, 10)
+		meta["Shadow-Rays"] = strconv.FormatUint(w.Stats.Shadow, 10)
+	}
+
+	return meta
+}
+
+// sourceHash hashes a scene along with the files it loads, or any other
+// file, such as a model, on its own.
+func sourceHash(filename string) (string, error) {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".yml", ".yaml":
+		return world.SceneHash(filename)
+	}
+
+	contents, err := os.ReadFile(filename)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%x", sha256.Sum256(contents)), nil
+}
+
+func runMetadata(args []string) error {
+	fs := newFlagSet("metadata", "<image.png>...")
+
+	files, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+
+	if len(files) == 0 {
+		fs.Usage()
+		return flag.ErrHelp
+	}
+
+	for i, f := range files {
+		meta, err := world.ReadPNGMetadata(f)
+		if err != nil {
+			return err
+		}
+
+		if len(files) > 1 {
+			if i > 0 {
+				fmt.Println()
+			}
+			fmt.Printf("%s:\n", f)
+		}
+
+		if len(meta) == 0 {
+			fmt.Println("no metadata")
+			continue
+		}
+
+		keys := make([]string, 0, len(meta))
+		for k := range meta {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		for _, k := range keys {
+			fmt.Printf("%-17s %s\n", k+":", meta[k])
+		}
+	}
+
+	return nil
+}
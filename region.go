@@ -0,0 +1,27 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/dannyroes/raytrace/world"
+)
+
+// loadPatch reads the image a region is written into with -patch, which must
+// be the size of the camera's full frame.
+func loadPatch(filename string, c *world.CameraType) (world.CanvasType, error) {
+	if c.Region == nil {
+		return world.CanvasType{}, errors.New("-patch needs -region")
+	}
+
+	base, err := world.LoadPNG(filename)
+	if err != nil {
+		return base, err
+	}
+
+	if base.Width != c.HSize || base.Height != c.VSize {
+		return base, fmt.Errorf("%s is %dx%d, expected the full %dx%d frame", filename, base.Width, base.Height, c.HSize, c.VSize)
+	}
+
+	return base, nil
+}
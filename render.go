@@ -0,0 +1,402 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dannyroes/raytrace/sampler"
+	"github.com/dannyroes/raytrace/world"
+)
+
+type renderOptions struct {
+	output      string
+	width       int
+	height      int
+	supersample int
+	workers     int
+	tileSize    int
+	tileOrder   string
+	depth       int
+	quiet       bool
+	preview     bool
+	adaptive    world.AdaptiveSampling
+	sampler     string
+	seed        int64
+	filter      string
+	radius      float64
+	region      string
+}
+
+func (o *renderOptions) register(fs *flag.FlagSet, output string) {
+	fs.StringVar(&o.output, "o", output, "output image (.png or .ppm)")
+	fs.IntVar(&o.width, "width", 0, "image width, overrides the scene camera")
+	fs.IntVar(&o.height, "height", 0, "image height, overrides the scene camera")
+	fs.IntVar(&o.supersample, "supersample", 0, "supersample factor, overrides the scene camera")
+	fs.IntVar(&o.workers, "workers", 0, "number of render workers (0 picks from the CPU count)")
+	fs.IntVar(&o.tileSize, "tile-size", world.DefaultTileSize, "side of the square tiles the image is rendered in")
+	fs.StringVar(&o.tileOrder, "tile-order", "scanline", "order tiles are rendered in: scanline, spiral or hilbert")
+	fs.IntVar(&o.adaptive.MinSamples, "min-samples", 0, "adaptive sampling: samples every pixel starts with, enables adaptive sampling in place of -supersample")
+	fs.IntVar(&o.adaptive.MaxSamples, "max-samples", 64, "adaptive sampling: most samples a pixel can take")
+	fs.Float64Var(&o.adaptive.Threshold, "adaptive-threshold", world.DefaultAdaptiveThreshold, "adaptive sampling: standard error of a pixel's colour to stop at")
+	fs.StringVar(&o.sampler, "sampler", "", "place samples with uniform, stratified, halton or sobol instead of a regular grid")
+	fs.Int64Var(&o.seed, "sampler-seed", 0, "seed for -sampler")
+	fs.StringVar(&o.filter, "filter", "", "reconstruct pixels from their samples with box, tent, gaussian, mitchell or lanczos")
+	fs.Float64Var(&o.radius, "filter-radius", 0, "radius of -filter in pixels (0 uses the filter's usual radius)")
+	fs.StringVar(&o.region, "region", "", "render only x,y,width,height of the frame, in pixels or as fractions like 0.25,0.25,0.5,0.5")
+	fs.IntVar(&o.depth, "depth", -1, "maximum reflection/refraction depth (-1 keeps the default)")
+	fs.BoolVar(&o.quiet, "q", false, "suppress progress output")
+	fs.BoolVar(&o.preview, "preview", false, "draw a live preview in the terminal instead of the progress line")
+}
+
+// apply copies any options set on the command line over the values loaded
+// from the scene file.
+func (o *renderOptions) apply(c *world.CameraType) error {
+	if c.Transform == nil {
+		return errors.New("scene has no camera")
+	}
+
+	if o.width > 0 {
+		c.HSize = o.width
+	}
+	if o.height > 0 {
+		c.VSize = o.height
+	}
+	if c.HSize <= 0 || c.VSize <= 0 {
+		return fmt.Errorf("invalid image size %dx%d", c.HSize, c.VSize)
+	}
+	c.CalcPixelSize()
+
+	if o.region != "" {
+		region, err := world.ParseRegion(o.region, c.HSize, c.VSize)
+		if err != nil {
+			return err
+		}
+		c.Region = &region
+	}
+
+	if o.supersample > 0 {
+		c.Supersample = o.supersample
+	}
+	if o.workers > 0 {
+		c.Workers = o.workers
+	}
+	if o.tileSize > 0 {
+		c.TileSize = o.tileSize
+	}
+	if o.tileOrder != "" {
+		order, err := world.ParseTileOrder(o.tileOrder)
+		if err != nil {
+			return err
+		}
+		c.TileOrder = order
+	}
+	if o.adaptive.MinSamples > 0 {
+		err := o.adaptive.Validate()
+		if err != nil {
+			return err
+		}
+		adaptive := o.adaptive
+		c.Adaptive = &adaptive
+	}
+	if o.filter != "" {
+		kind, err := world.ParseFilterKind(o.filter)
+		if err != nil {
+			return err
+		}
+		if c.Adaptive != nil {
+			return errors.New("-filter cannot be combined with adaptive sampling")
+		}
+		c.Filter = &world.Filter{Kind: kind, Radius: o.radius}
+	}
+	if o.sampler != "" {
+		kind, err := sampler.ParseKind(o.sampler)
+		if err != nil {
+			return err
+		}
+		samples := c.Supersample * c.Supersample
+		if c.Adaptive != nil {
+			samples = c.Adaptive.MaxSamples
+		}
+		c.Sampler = sampler.New(kind, samples, o.seed)
+	}
+	if o.depth >= 0 {
+		c.MaxDepth = o.depth
+	}
+	c.Observer = nil
+	if !o.quiet {
+		c.Observer = world.NewProgressPrinter(os.Stdout)
+	}
+	if o.preview && !o.quiet && isTerminal(os.Stdout) {
+		columns, rows := terminalSize()
+		c.Observer = world.NewTerminalPreview(os.Stdout, columns, rows)
+		c.ProgressInterval = 250 * time.Millisecond
+	}
+
+	return nil
+}
+
+func runRender(args []string) error {
+	var opts renderOptions
+	var cacheOpts cacheOptions
+	var checkpointOpts checkpointOptions
+	var progressive bool
+	var interval time.Duration
+	var sampleMap string
+	var stream bool
+	var budget time.Duration
+	var patch string
+	var workers string
+
+	fs := newFlagSet("render", "<scene.yml>")
+	opts.register(fs, "output/scene.png")
+	cacheOpts.register(fs)
+	checkpointOpts.register(fs)
+	fs.BoolVar(&progressive, "progressive", false, "render a coarse image first and refine it in passes, interrupt to keep the image so far")
+	fs.DurationVar(&interval, "progressive-interval", 10*time.Second, "how often to write the image so far in progressive mode")
+	fs.StringVar(&sampleMap, "sample-map", "", "with adaptive sampling, also write an image of the samples taken per pixel")
+	fs.BoolVar(&stream, "stream", false, "render through a file beside the output instead of memory, for images too large to hold, skipping the cache")
+	fs.StringVar(&workers, "farm", "", "render on these worker URLs, comma separated, see the worker command")
+	fs.StringVar(&patch, "patch", "", "with -region, write the region into a copy of this PNG, which must be the full frame, instead of a cropped image")
+	fs.DurationVar(&budget, "budget", 0, "render in about this long, picking samples and depth to fit and keeping the best image so far, skipping the cache")
+
+	files, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+
+	if len(files) != 1 {
+		fs.Usage()
+		return flag.ErrHelp
+	}
+
+	err = checkImageFormat(opts.output)
+	if err != nil {
+		return err
+	}
+
+	if sampleMap != "" {
+		err = checkImageFormat(sampleMap)
+		if err != nil {
+			return err
+		}
+	}
+
+	c, w, err := loadScene(files[0], &opts)
+	if err != nil {
+		return err
+	}
+
+	if (c.Adaptive != nil || c.Filter != nil) && progressive {
+		return errors.New("progressive rendering cannot be combined with adaptive sampling or -filter")
+	}
+	if c.Adaptive == nil && sampleMap != "" {
+		return errors.New("-sample-map needs adaptive sampling, set -min-samples")
+	}
+	// Resumed tiles don't keep the samples each pixel took.
+	if sampleMap != "" && checkpointOpts.resume {
+		return errors.New("-sample-map cannot be combined with -resume, resumed tiles have no sample counts")
+	}
+	if progressive && checkpointOpts.file != "" {
+		return errors.New("progressive rendering cannot be combined with -checkpoint")
+	}
+	// The preview keeps a full size copy of the image, which streaming is
+	// there to avoid.
+	if stream && (progressive || opts.preview || sampleMap != "" || checkpointOpts.file != "") {
+		return errors.New("-stream cannot be combined with -progressive, -preview, -sample-map or -checkpoint")
+	}
+
+	if budget > 0 && (progressive || stream || sampleMap != "" || checkpointOpts.file != "" || c.Adaptive != nil || c.Filter != nil) {
+		return errors.New("-budget cannot be combined with -progressive, -stream, -sample-map, -checkpoint, adaptive sampling or -filter")
+	}
+
+	if workers != "" && (progressive || stream || budget > 0 || sampleMap != "" || checkpointOpts.file != "") {
+		return errors.New("-farm cannot be combined with -progressive, -stream, -budget, -sample-map or -checkpoint")
+	}
+
+	var base world.CanvasType
+	if patch != "" {
+		if stream {
+			return errors.New("-patch cannot be combined with -stream")
+		}
+		base, err = loadPatch(patch, c)
+		if err != nil {
+			return err
+		}
+	}
+	// output is the image written for a render, patched into base if set.
+	output := func(image world.CanvasType) world.CanvasType {
+		if patch == "" {
+			return image
+		}
+		base.Draw(image, c.Region.X, c.Region.Y)
+		return base
+	}
+
+	c.Checkpoint, err = checkpointOpts.open(files[0], c, opts.quiet)
+	if err != nil {
+		return err
+	}
+
+	// Progressive, budgeted, farmed and checkpointed renders stop cleanly
+	// when interrupted.
+	ctx := context.Background()
+	if progressive || budget > 0 || workers != "" || c.Checkpoint != nil {
+		var stop context.CancelFunc
+		ctx, stop = signal.NotifyContext(ctx, os.Interrupt)
+		defer stop()
+	}
+
+	if stream {
+		return renderStreamed(ctx, files[0], c, w, opts.output)
+	}
+
+	var counts world.SampleCounts
+	render := func(w world.WorldType) (world.CanvasType, error) {
+		switch {
+		case progressive:
+			return c.RenderProgressive(ctx, w, interval, func(image world.CanvasType) {
+				err := writePartial(output(image), opts.output)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Could not write image so far: %v\n", err)
+				}
+			})
+		case workers != "":
+			return renderFarm(ctx, files[0], c, w, strings.Split(workers, ","), &opts)
+		case sampleMap != "":
+			image, samples, err := c.RenderAdaptive(ctx, w)
+			counts = samples
+			return image, err
+		}
+		return c.RenderContext(ctx, w)
+	}
+
+	var image world.CanvasType
+	var meta map[string]string
+	if budget > 0 {
+		image, meta, err = renderBudget(ctx, files[0], c, w, budget, opts.quiet)
+	} else {
+		image, meta, err = renderCached(files[0], c, w, &cacheOpts, opts.quiet, render)
+	}
+	if c.Checkpoint != nil && c.Checkpoint.Err() != nil {
+		fmt.Fprintf(os.Stderr, "Could not save checkpoint: %v\n", c.Checkpoint.Err())
+	}
+	if err == context.Canceled && c.Checkpoint != nil {
+		return fmt.Errorf("render interrupted with %d tiles saved to %s, continue it with -resume", c.Checkpoint.Finished(), checkpointOpts.file)
+	}
+	if err == context.Canceled {
+		fmt.Println("Render interrupted, writing the image so far")
+		err = nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if sampleMap != "" {
+		err = writeSampleMap(counts, c.Adaptive.MaxSamples, sampleMap)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = writeImage(output(image), opts.output, meta)
+	if err != nil || c.Checkpoint == nil {
+		return err
+	}
+
+	return c.Checkpoint.Remove()
+}
+
+func writeSampleMap(counts world.SampleCounts, max int, filename string) error {
+	if counts.Counts == nil {
+		fmt.Fprintln(os.Stderr, "The cached render has no sample counts, use -force to render again for -sample-map")
+		return nil
+	}
+
+	fmt.Printf("Took %.2f samples per pixel on average, up to %d\n", float64(counts.Total())/float64(len(counts.Counts)), max)
+	return writeImage(counts.Image(max), filename, nil)
+}
+
+// writePartial replaces the output with an unfinished image, writing it
+// beside the output first so a viewer never sees a half written file.
+func writePartial(image world.CanvasType, filename string) error {
+	ext := filepath.Ext(filename)
+	tmp := strings.TrimSuffix(filename, ext) + ".partial" + ext
+
+	err := writeImage(image, tmp, nil)
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, filename)
+}
+
+func loadScene(filename string, opts *renderOptions) (*world.CameraType, world.WorldType, error) {
+	c, w, err := world.LoadScene(filename)
+	if err != nil {
+		return c, w, err
+	}
+
+	err = opts.apply(c)
+	if err != nil {
+		return c, w, fmt.Errorf("%s: %v", filename, err)
+	}
+
+	return c, w, nil
+}
+
+func checkImageFormat(filename string) error {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".png", ".ppm":
+		return nil
+	}
+
+	return fmt.Errorf("unsupported output format %q", filepath.Ext(filename))
+}
+
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// terminalSize returns the space available for the preview, read from the
+// COLUMNS and LINES variables most shells set. Rows are left for the status
+// line and the prompt.
+func terminalSize() (int, int) {
+	columns, err := strconv.Atoi(os.Getenv("COLUMNS"))
+	if err != nil || columns <= 0 {
+		columns = 80
+	}
+
+	lines, err := strconv.Atoi(os.Getenv("LINES"))
+	if err != nil || lines <= 2 {
+		lines = 24
+	}
+
+	return columns, lines - 2
+}
+
+// writeImage saves the image as PPM or PNG depending on the extension. meta
+// is stored in PNG files and ignored for PPM.
+func writeImage(image world.CanvasType, filename string, meta map[string]string) error {
+	if strings.ToLower(filepath.Ext(filename)) == ".ppm" {
+		return image.ToPPMFile(filename)
+	}
+
+	if meta != nil {
+		return image.ToPNGWithMetadata(filename, meta)
+	}
+
+	return image.ToPNG(filename)
+}
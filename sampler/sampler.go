@@ -0,0 +1,237 @@
+package sampler
+
+import (
+	"fmt"
+	"math"
+)
+
+// Sampler generates points in [0, 1) x [0, 1). The point returned depends
+// only on the seed, the pixel, the index of the sample within the pixel and
+// the dimension, so an image comes out the same however its pixels are
+// shared between workers. Dimension 0 places the sample within the pixel,
+// later dimensions are for lens, light and material sampling.
+type Sampler interface {
+	Sample2D(x, y, index, dimension int) (float64, float64)
+	String() string
+}
+
+type Kind int
+
+const (
+	KindUniform Kind = iota
+	KindStratified
+	KindHalton
+	KindSobol
+)
+
+var kinds = []Kind{KindUniform, KindStratified, KindHalton, KindSobol}
+
+func (k Kind) String() string {
+	switch k {
+	case KindUniform:
+		return "uniform"
+	case KindStratified:
+		return "stratified"
+	case KindHalton:
+		return "halton"
+	case KindSobol:
+		return "sobol"
+	}
+
+	return "unknown"
+}
+
+func ParseKind(name string) (Kind, error) {
+	for _, k := range kinds {
+		if k.String() == name {
+			return k, nil
+		}
+	}
+
+	return KindUniform, fmt.Errorf("unknown sampler %q, expected uniform, stratified, halton or sobol", name)
+}
+
+// New returns a sampler of the given kind for pixels taking samples samples
+// each. Only the stratified sampler uses the count, to size its grid.
+func New(kind Kind, samples int, seed int64) Sampler {
+	switch kind {
+	case KindStratified:
+		return Stratified(samples, seed)
+	case KindHalton:
+		return Halton(seed)
+	case KindSobol:
+		return Sobol(seed)
+	}
+
+	return Uniform(seed)
+}
+
+// UniformSampler picks every point independently at random.
+type UniformSampler struct {
+	Seed int64
+}
+
+func Uniform(seed int64) UniformSampler {
+	return UniformSampler{Seed: seed}
+}
+
+func (s UniformSampler) Sample2D(x, y, index, dimension int) (float64, float64) {
+	h := hash(s.Seed, x, y, index, dimension)
+	return toFloat(h), toFloat(mix(h))
+}
+
+func (s UniformSampler) String() string {
+	return fmt.Sprintf("uniform seed %d", s.Seed)
+}
+
+// StratifiedSampler splits the pixel into a grid with a cell for each
+// sample and jitters each point within its cell. The grid is as small as
+// possible while having at least Samples cells; samples past the end of the
+// grid start over with fresh jitter.
+type StratifiedSampler struct {
+	Samples int
+	Seed    int64
+	n       int
+}
+
+func Stratified(samples int, seed int64) StratifiedSampler {
+	if samples < 1 {
+		samples = 1
+	}
+
+	n := int(math.Ceil(math.Sqrt(float64(samples))))
+	return StratifiedSampler{Samples: samples, Seed: seed, n: n}
+}
+
+func (s StratifiedSampler) Sample2D(x, y, index, dimension int) (float64, float64) {
+	cells := s.n * s.n
+
+	// Rotating the cells per pixel and dimension stops the same cell of
+	// every dimension being used together.
+	rotation := int(hash(s.Seed, x, y, dimension) % uint64(cells))
+	cell := (index + rotation) % cells
+
+	h := hash(s.Seed, x, y, index, dimension)
+	u := (float64(cell%s.n) + toFloat(h)) / float64(s.n)
+	v := (float64(cell/s.n) + toFloat(mix(h))) / float64(s.n)
+
+	return u, v
+}
+
+func (s StratifiedSampler) String() string {
+	return fmt.Sprintf("stratified %d seed %d", s.Samples, s.Seed)
+}
+
+// haltonPrimes are the bases of the Halton sequence, two per dimension.
+var haltonPrimes = []int{2, 3, 5, 7, 11, 13, 17, 19, 23, 29, 31, 37, 41, 43, 47, 53}
+
+// HaltonSampler uses the Halton sequence, with bases 2 and 3 for dimension
+// 0, 5 and 7 for dimension 1 and so on. Each pixel's sequence is shifted by a
+// random amount so neighbouring pixels do not repeat one another.
+type HaltonSampler struct {
+	Seed int64
+}
+
+func Halton(seed int64) HaltonSampler {
+	return HaltonSampler{Seed: seed}
+}
+
+func (s HaltonSampler) Sample2D(x, y, index, dimension int) (float64, float64) {
+	d := dimension % (len(haltonPrimes) / 2)
+	h := hash(s.Seed, x, y, dimension)
+
+	u := rotate(radicalInverse(haltonPrimes[2*d], index), toFloat(h))
+	v := rotate(radicalInverse(haltonPrimes[2*d+1], index), toFloat(mix(h)))
+
+	return u, v
+}
+
+func (s HaltonSampler) String() string {
+	return fmt.Sprintf("halton seed %d", s.Seed)
+}
+
+// SobolSampler uses the first two dimensions of the Sobol sequence for
+// every dimension, scrambled with a random XOR per pixel and dimension.
+type SobolSampler struct {
+	Seed int64
+}
+
+func Sobol(seed int64) SobolSampler {
+	return SobolSampler{Seed: seed}
+}
+
+func (s SobolSampler) Sample2D(x, y, index, dimension int) (float64, float64) {
+	u, v := sobol2(uint32(index))
+	h := hash(s.Seed, x, y, dimension)
+
+	u ^= uint32(h)
+	v ^= uint32(h >> 32)
+
+	return float64(u) / (1 << 32), float64(v) / (1 << 32)
+}
+
+func (s SobolSampler) String() string {
+	return fmt.Sprintf("sobol seed %d", s.Seed)
+}
+
+// sobol2 returns the i'th point of the two dimensional Sobol sequence as
+// 32 bit fractions. The first dimension is the van der Corput sequence.
+func sobol2(i uint32) (uint32, uint32) {
+	var u, v uint32
+	dir := uint32(1) << 31
+
+	for bit := 0; i != 0; bit, i = bit+1, i>>1 {
+		if i&1 != 0 {
+			u ^= uint32(1) << (31 - bit)
+			v ^= dir
+		}
+		dir ^= dir >> 1
+	}
+
+	return u, v
+}
+
+// radicalInverse mirrors the digits of i in the given base about the point.
+func radicalInverse(base, i int) float64 {
+	inverse := 0.0
+	scale := 1.0 / float64(base)
+
+	for ; i > 0; i /= base {
+		inverse += float64(i%base) * scale
+		scale /= float64(base)
+	}
+
+	return inverse
+}
+
+// rotate adds offset to t, wrapping around at 1.
+func rotate(t, offset float64) float64 {
+	t += offset
+	if t >= 1 {
+		t--
+	}
+
+	return t
+}
+
+// hash combines the seed with the values using the SplitMix64 finaliser.
+func hash(seed int64, values ...int) uint64 {
+	h := mix(uint64(seed))
+	for _, v := range values {
+		h = mix(h ^ uint64(v))
+	}
+
+	return h
+}
+
+func mix(v uint64) uint64 {
+	v += 0x9e3779b97f4a7c15
+	v = (v ^ v>>30) * 0xbf58476d1ce4e5b9
+	v = (v ^ v>>27) * 0x94d049bb133111eb
+	return v ^ v>>31
+}
+
+// toFloat returns the top 53 bits of h as a float in [0, 1).
+func toFloat(h uint64) float64 {
+	return float64(h>>11) / (1 << 53)
+}
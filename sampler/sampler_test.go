@@ -0,0 +1,104 @@
+package sampler
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSamplersInRangeAndDeterministic(t *testing.T) {
+	for _, k := range kinds {
+		s := New(k, 16, 42)
+		again := New(k, 16, 42)
+
+		for i := 0; i < 64; i++ {
+			for d := 0; d < 3; d++ {
+				u, v := s.Sample2D(3, 7, i, d)
+				if u < 0 || u >= 1 || v < 0 || v >= 1 {
+					t.Fatalf("%s: sample %d dimension %d out of range: %f, %f", k, i, d, u, v)
+				}
+
+				au, av := again.Sample2D(3, 7, i, d)
+				if u != au || v != av {
+					t.Fatalf("%s: expected the same sample from the same seed, received %f, %f and %f, %f", k, u, v, au, av)
+				}
+			}
+		}
+	}
+}
+
+func TestSamplersVaryByPixelAndSeed(t *testing.T) {
+	for _, k := range kinds {
+		u, v := New(k, 16, 1).Sample2D(0, 0, 1, 0)
+
+		pu, pv := New(k, 16, 1).Sample2D(1, 0, 1, 0)
+		if u == pu && v == pv {
+			t.Errorf("%s: expected neighbouring pixels to differ", k)
+		}
+
+		su, sv := New(k, 16, 2).Sample2D(0, 0, 1, 0)
+		if u == su && v == sv {
+			t.Errorf("%s: expected seeds to differ", k)
+		}
+	}
+}
+
+func TestStratifiedCoversEveryCell(t *testing.T) {
+	s := Stratified(9, 5)
+	cells := map[[2]int]bool{}
+
+	for i := 0; i < 9; i++ {
+		u, v := s.Sample2D(2, 2, i, 0)
+		cells[[2]int{int(u * 3), int(v * 3)}] = true
+	}
+
+	if len(cells) != 9 {
+		t.Errorf("Expected a sample in each of 9 cells, received %d", len(cells))
+	}
+}
+
+func TestSobolSequence(t *testing.T) {
+	expected := [][2]float64{{0, 0}, {0.5, 0.5}, {0.25, 0.75}, {0.75, 0.25}, {0.125, 0.625}}
+
+	for i, e := range expected {
+		u, v := sobol2(uint32(i))
+		fu, fv := float64(u)/(1<<32), float64(v)/(1<<32)
+		if fu != e[0] || fv != e[1] {
+			t.Errorf("Point %d mismatch expected %v received %f, %f", i, e, fu, fv)
+		}
+	}
+}
+
+func TestRadicalInverse(t *testing.T) {
+	tests := []struct {
+		base     int
+		i        int
+		expected float64
+	}{
+		{2, 1, 0.5},
+		{2, 2, 0.25},
+		{2, 3, 0.75},
+		{3, 1, 1.0 / 3},
+		{3, 4, 1.0/3 + 1.0/9},
+	}
+
+	for _, test := range tests {
+		r := radicalInverse(test.base, test.i)
+		if math.Abs(r-test.expected) > 1e-12 {
+			t.Errorf("Base %d index %d mismatch expected %f received %f", test.base, test.i, test.expected, r)
+		}
+	}
+}
+
+func TestParseKind(t *testing.T) {
+	for _, k := range kinds {
+		parsed, err := ParseKind(k.String())
+		if err != nil || parsed != k {
+			t.Errorf("Parse mismatch expected %s received %s, %v", k, parsed, err)
+		}
+	}
+
+	_, err := ParseKind("blue-noise")
+	if err == nil {
+		t.Error("Expected error for an unknown sampler")
+	}
+}
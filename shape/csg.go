@@ -72,7 +72,31 @@ func (c *CsgType) SetParent(p Shape) {
 }
 
 func (c *CsgType) Bounds() Bounds {
-	return EmptyBounds()
+	return TransformBounds(c.left.Bounds(), c.left.GetTransform()).Union(TransformBounds(c.right.Bounds(), c.right.GetTransform()))
+}
+
+func (c *CsgType) Operation() CsgOperation {
+	return c.operation
+}
+
+func (c *CsgType) Left() Shape {
+	return c.left
+}
+
+func (c *CsgType) Right() Shape {
+	return c.right
+}
+
+func (op CsgOperation) String() string {
+	switch op {
+	case CsgUnion:
+		return "union"
+	case CsgIntersection:
+		return "intersection"
+	case CsgDifference:
+		return "difference"
+	}
+	return "unknown"
 }
 
 func (c *CsgType) CastsShadow() bool {
@@ -145,3 +145,17 @@ func TestCsgIntersect(t *testing.T) {
 		}
 	}
 }
+
+func TestCsgBounds(t *testing.T) {
+	s1 := Sphere()
+	s2 := Cube()
+	s2.SetTransform(data.Translation(2, 3, 4))
+
+	csg := Csg(CsgDifference, s1, s2)
+	b := csg.Bounds()
+
+	expected := Bounds{Min: data.Point(-1, -1, -1), Max: data.Point(3, 4, 5)}
+	if !data.TupleEqual(b.Min, expected.Min) || !data.TupleEqual(b.Max, expected.Max) {
+		t.Errorf("Bounds mismatch expected %+v received %+v", expected, b)
+	}
+}
@@ -79,30 +79,7 @@ func (g *GroupType) Bounds() Bounds {
 	if g.GroupBounds == nil {
 		b := EmptyBounds()
 		for _, c := range g.Children {
-			points := boundsToPoints(c.Bounds().Min, c.Bounds().Max)
-			for _, p := range points {
-				t := c.GetTransform().MultiplyTuple(p)
-				if t.X < b.Min.X {
-					b.Min.X = t.X
-				}
-				if t.X > b.Max.X {
-					b.Max.X = t.X
-				}
-
-				if t.Y < b.Min.Y {
-					b.Min.Y = t.Y
-				}
-				if t.Y > b.Max.Y {
-					b.Max.Y = t.Y
-				}
-
-				if t.Z < b.Min.Z {
-					b.Min.Z = t.Z
-				}
-				if t.Z > b.Max.Z {
-					b.Max.Z = t.Z
-				}
-			}
+			b = b.Union(TransformBounds(c.Bounds(), c.GetTransform()))
 		}
 		g.GroupBounds = &b
 	}
@@ -158,6 +135,58 @@ func EmptyBounds() Bounds {
 	}
 }
 
+// TransformBounds returns the axis aligned box containing b once transformed
+// by m. Axes that become undefined, such as a rotated infinite plane, are
+// treated as unbounded.
+func TransformBounds(b Bounds, m data.Matrix) Bounds {
+	res := EmptyBounds()
+
+	for _, p := range boundsToPoints(b.Min, b.Max) {
+		t := m.MultiplyTuple(p)
+		res = res.Union(Bounds{Min: t, Max: t})
+	}
+
+	if math.IsNaN(res.Min.X) || math.IsNaN(res.Max.X) {
+		res.Min.X, res.Max.X = math.Inf(-1), math.Inf(1)
+	}
+	if math.IsNaN(res.Min.Y) || math.IsNaN(res.Max.Y) {
+		res.Min.Y, res.Max.Y = math.Inf(-1), math.Inf(1)
+	}
+	if math.IsNaN(res.Min.Z) || math.IsNaN(res.Max.Z) {
+		res.Min.Z, res.Max.Z = math.Inf(-1), math.Inf(1)
+	}
+
+	return res
+}
+
+func (b Bounds) Union(o Bounds) Bounds {
+	return Bounds{
+		Min: data.Point(boundMin(b.Min.X, o.Min.X), boundMin(b.Min.Y, o.Min.Y), boundMin(b.Min.Z, o.Min.Z)),
+		Max: data.Point(boundMax(b.Max.X, o.Max.X), boundMax(b.Max.Y, o.Max.Y), boundMax(b.Max.Z, o.Max.Z)),
+	}
+}
+
+func (b Bounds) IsEmpty() bool {
+	return b.Min.X > b.Max.X || b.Min.Y > b.Max.Y || b.Min.Z > b.Max.Z
+}
+
+// boundMin and boundMax let NaN win so that it can be detected afterwards.
+func boundMin(a, b float64) float64 {
+	if math.IsNaN(a) || math.IsNaN(b) {
+		return math.NaN()
+	}
+
+	return math.Min(a, b)
+}
+
+func boundMax(a, b float64) float64 {
+	if math.IsNaN(a) || math.IsNaN(b) {
+		return math.NaN()
+	}
+
+	return math.Max(a, b)
+}
+
 func boundsToPoints(min, max data.Tuple) []data.Tuple {
 	points := make([]data.Tuple, 8)
 	var pointX, pointY, pointZ float64
@@ -259,3 +259,18 @@ func TestGroupBounds(t *testing.T) {
 		}
 	}
 }
+
+func TestTransformBoundsInfinite(t *testing.T) {
+	p := Plane()
+	b := TransformBounds(p.Bounds(), data.RotateX(math.Pi/2))
+
+	if !math.IsInf(b.Min.Y, -1) || !math.IsInf(b.Max.Y, 1) {
+		t.Errorf("Expected rotated plane to be unbounded in y, received %+v", b)
+	}
+
+	for _, v := range []float64{b.Min.X, b.Min.Y, b.Min.Z, b.Max.X, b.Max.Y, b.Max.Z} {
+		if math.IsNaN(v) {
+			t.Errorf("Expected no NaN in bounds, received %+v", b)
+		}
+	}
+}
@@ -3,6 +3,7 @@ package shape
 import (
 	"bufio"
 	"fmt"
+	"io"
 	"os"
 	"strconv"
 	"strings"
@@ -24,7 +25,13 @@ func ParseObj(file string) (res OBJDetails) {
 	if err != nil {
 		panic(err)
 	}
-	scanner := bufio.NewScanner(f)
+	defer f.Close()
+
+	return ParseObjReader(f)
+}
+
+func ParseObjReader(reader io.Reader) (res OBJDetails) {
+	scanner := bufio.NewScanner(reader)
 
 	r := &res
 	r.Groups = make(map[string]*GroupType)
@@ -51,6 +58,30 @@ func ParseObj(file string) (res OBJDetails) {
 	return res
 }
 
+// LoadObj parses an OBJ file like ParseObj but reports problems with the file
+// as an error rather than a panic.
+func LoadObj(file string) (res OBJDetails, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%s: %v", file, r)
+		}
+	}()
+
+	return ParseObj(file), nil
+}
+
+// LoadObjReader is LoadObj for OBJ data that is not on disk. name is only
+// used in error messages.
+func LoadObjReader(name string, reader io.Reader) (res OBJDetails, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%s: %v", name, r)
+		}
+	}()
+
+	return ParseObjReader(reader), nil
+}
+
 func (o *OBJDetails) AddVertex(loc []string) {
 	for loc[0] == "" {
 		loc = loc[1:]
@@ -2,6 +2,9 @@ package shape
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/dannyroes/raytrace/data"
@@ -162,6 +165,49 @@ func TestObjGroups(t *testing.T) {
 	}
 }
 
+func TestLoadObj(t *testing.T) {
+	res, err := LoadObj("tests/triangles.obj")
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	if res.Triangles != 2 {
+		t.Errorf("Triangle count mismatch expected %d received %d", 2, res.Triangles)
+	}
+
+	_, err = LoadObj("tests/missing.obj")
+	if err == nil {
+		t.Error("Expected error loading missing file")
+	}
+
+	broken := filepath.Join(t.TempDir(), "broken.obj")
+	err = os.WriteFile(broken, []byte("v 0 0 0\nv 1 0 0\nf 1 2\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = LoadObj(broken)
+	if err == nil {
+		t.Error("Expected error loading face with too few indexes")
+	}
+}
+
+func TestLoadObjReader(t *testing.T) {
+	res, err := LoadObjReader("inline", strings.NewReader("v 0 0 0\nv 1 0 0\nv 0 1 0\nf 1 2 3\n"))
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	if res.Triangles != 1 {
+		t.Errorf("Triangle count mismatch expected %d received %d", 1, res.Triangles)
+	}
+
+	_, err = LoadObjReader("inline", strings.NewReader("f 1 2 3\n"))
+	if err == nil {
+		t.Error("Expected error for face referencing missing vertices")
+	}
+}
+
 func OBJDetailsEqual(a, b OBJDetails) bool {
 	if a.Ignored != b.Ignored {
 		return false
@@ -0,0 +1,47 @@
+package shape
+
+// Walk calls fn for s and every shape nested inside it, descending into group
+// children and both sides of CSG shapes. depth is 0 for s itself.
+func Walk(s Shape, fn func(s Shape, depth int)) {
+	walk(s, 0, fn)
+}
+
+func walk(s Shape, depth int, fn func(s Shape, depth int)) {
+	fn(s, depth)
+
+	switch v := s.(type) {
+	case *GroupType:
+		for _, c := range v.Children {
+			walk(c, depth+1, fn)
+		}
+	case *CsgType:
+		walk(v.left, depth+1, fn)
+		walk(v.right, depth+1, fn)
+	}
+}
+
+// Kind returns the short name used for a shape in scene files and reports.
+func Kind(s Shape) string {
+	switch v := s.(type) {
+	case *SphereType:
+		return "sphere"
+	case *PlaneType:
+		return "plane"
+	case *CubeType:
+		return "cube"
+	case *CylinderType:
+		return "cylinder"
+	case *ConeType:
+		return "cone"
+	case *TriangleType:
+		if v.smooth {
+			return "smooth-triangle"
+		}
+		return "triangle"
+	case *GroupType:
+		return "group"
+	case *CsgType:
+		return "csg"
+	}
+	return "unknown"
+}
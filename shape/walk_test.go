@@ -0,0 +1,56 @@
+package shape
+
+import "testing"
+
+func TestWalk(t *testing.T) {
+	s1 := Sphere()
+	s2 := Cube()
+	s3 := Cylinder()
+	csg := Csg(CsgUnion, s2, s3)
+
+	g := Group()
+	g.AddChild(s1, csg)
+
+	type visit struct {
+		s     Shape
+		depth int
+	}
+	expected := []visit{{g, 0}, {s1, 1}, {csg, 1}, {s2, 2}, {s3, 2}}
+
+	var visits []visit
+	Walk(g, func(s Shape, depth int) {
+		visits = append(visits, visit{s, depth})
+	})
+
+	if len(visits) != len(expected) {
+		t.Fatalf("Visit count mismatch expected %d received %d", len(expected), len(visits))
+	}
+
+	for i := range expected {
+		if visits[i] != expected[i] {
+			t.Errorf("Visit %d mismatch expected %+v received %+v", i, expected[i], visits[i])
+		}
+	}
+}
+
+func TestKind(t *testing.T) {
+	cases := []struct {
+		s        Shape
+		expected string
+	}{
+		{Sphere(), "sphere"},
+		{Plane(), "plane"},
+		{Cube(), "cube"},
+		{Cylinder(), "cylinder"},
+		{Cone(), "cone"},
+		{Group(), "group"},
+		{Csg(CsgUnion, Sphere(), Cube()), "csg"},
+		{&MockShape{}, "unknown"},
+	}
+
+	for _, tc := range cases {
+		if k := Kind(tc.s); k != tc.expected {
+			t.Errorf("Kind mismatch expected %s received %s", tc.expected, k)
+		}
+	}
+}
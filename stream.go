@@ -0,0 +1,63 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/dannyroes/raytrace/world"
+)
+
+// renderStreamed renders the scene into a tile file beside the output and
+// then encodes it a row at a time, so the image is never held in memory.
+func renderStreamed(ctx context.Context, scene string, c *world.CameraType, w world.WorldType, output string) error {
+	tmp := output + ".tiles"
+	width, height := c.ImageSize()
+	tiles, err := world.CreateTileFile(tmp, width, height)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp)
+	defer tiles.Close()
+
+	w.Stats = &world.RayStats{}
+	start := time.Now()
+
+	err = c.RenderTo(ctx, w, tiles)
+	if err != nil {
+		return err
+	}
+
+	return writeStreamed(tiles, output, renderMetadata(scene, c, w, time.Since(start)))
+}
+
+// writeStreamed encodes the tile file as PPM or PNG depending on the
+// extension, like writeImage.
+func writeStreamed(tiles *world.TileFile, filename string, meta map[string]string) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+
+	out := bufio.NewWriter(f)
+	if strings.ToLower(filepath.Ext(filename)) == ".ppm" {
+		err = tiles.WritePPM(out)
+	} else {
+		err = tiles.WritePNG(out, meta)
+	}
+	if err == nil {
+		err = out.Flush()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %v", filename, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"flag"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/dannyroes/raytrace/material"
+	"github.com/dannyroes/raytrace/shape"
+	"github.com/dannyroes/raytrace/world"
+)
+
+func runView(args []string) error {
+	var opts renderOptions
+
+	fs := newFlagSet("view", "<model.obj>")
+	opts.register(fs, "")
+
+	files, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+
+	if len(files) != 1 {
+		fs.Usage()
+		return flag.ErrHelp
+	}
+
+	if opts.output == "" {
+		name := strings.TrimSuffix(filepath.Base(files[0]), filepath.Ext(files[0]))
+		opts.output = filepath.Join("output", name+".png")
+	}
+
+	err = checkImageFormat(opts.output)
+	if err != nil {
+		return err
+	}
+
+	obj, err := shape.LoadObj(files[0])
+	if err != nil {
+		return err
+	}
+
+	model := obj.GetGroup()
+	model.SetMaterial(clayMaterial())
+
+	width, height := 400, 300
+	if opts.width > 0 {
+		width = opts.width
+	}
+	if opts.height > 0 {
+		height = opts.height
+	}
+
+	c, w, err := world.Studio(model, width, height)
+	if err != nil {
+		return err
+	}
+
+	err = opts.apply(c)
+	if err != nil {
+		return err
+	}
+
+	w.Stats = &world.RayStats{}
+	start := time.Now()
+	image := c.Render(w)
+
+	return writeImage(image, opts.output, renderMetadata(files[0], c, w, time.Since(start)))
+}
+
+func clayMaterial() material.MaterialType {
+	m := material.Material()
+	m.Colour = material.Colour(0.8, 0.78, 0.75)
+	m.Diffuse = 0.8
+	m.Specular = 0.3
+	m.Shininess = 50
+
+	return m
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/dannyroes/raytrace/world"
+)
+
+type fileState struct {
+	modTime time.Time
+	size    int64
+	exists  bool
+}
+
+func runWatch(args []string) error {
+	var opts renderOptions
+	var interval time.Duration
+	var scale float64
+
+	fs := newFlagSet("watch", "<scene.yml>")
+	opts.register(fs, "output/scene.png")
+	fs.DurationVar(&interval, "interval", 500*time.Millisecond, "how often to poll the scene files for changes")
+	fs.Float64Var(&scale, "scale", 1, "resolution multiplier for quicker previews, e.g. 0.5")
+
+	files, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+
+	if len(files) != 1 || scale <= 0 || interval <= 0 {
+		fs.Usage()
+		return flag.ErrHelp
+	}
+
+	err = checkImageFormat(opts.output)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	scene := files[0]
+	for {
+		deps, err := world.SceneFiles(scene)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
+		state := snapshotFiles(deps)
+
+		renderCtx, cancel := context.WithCancel(ctx)
+		done := make(chan error, 1)
+		go func() {
+			done <- watchRender(renderCtx, scene, &opts, scale)
+		}()
+
+		changed := waitForChange(ctx, deps, state, interval, cancel, done)
+
+		if !changed {
+			return nil
+		}
+		fmt.Printf("\nChange detected, reloading %s\n", scene)
+	}
+}
+
+func watchRender(ctx context.Context, scene string, opts *renderOptions, scale float64) error {
+	c, w, err := loadScene(scene, opts)
+	if err != nil {
+		return err
+	}
+
+	if scale != 1 {
+		width, height := c.HSize, c.VSize
+		c.HSize = scaleSize(width, scale)
+		c.VSize = scaleSize(height, scale)
+		c.CalcPixelSize()
+		// The region was given for the full size image.
+		if c.Region != nil {
+			region := c.Region.Scale(width, height, c.HSize, c.VSize)
+			c.Region = &region
+		}
+	}
+
+	w.Stats = &world.RayStats{}
+	start := time.Now()
+	image, err := c.RenderContext(ctx, w)
+	if err != nil {
+		return err
+	}
+
+	err = writeImage(image, opts.output, renderMetadata(scene, c, w, time.Since(start)))
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Wrote %s, waiting for changes\n", opts.output)
+	return nil
+}
+
+// waitForChange polls the files until one of them differs from state, then
+// cancels the in-flight render and waits for it to stop. Render failures are
+// reported as they arrive on done. The result is false if ctx ended first.
+func waitForChange(ctx context.Context, files []string, state map[string]fileState, interval time.Duration, cancel context.CancelFunc, done <-chan error) bool {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	rendering := true
+	changed := false
+
+	for !changed {
+		select {
+		case <-ctx.Done():
+			cancel()
+			if rendering {
+				<-done
+			}
+			return false
+		case err := <-done:
+			rendering = false
+			if err != nil && err != context.Canceled {
+				fmt.Fprintln(os.Stderr, err)
+			}
+		case <-ticker.C:
+			changed = filesChanged(state, snapshotFiles(files))
+		}
+	}
+
+	cancel()
+	if rendering {
+		<-done
+	}
+
+	return true
+}
+
+func snapshotFiles(files []string) map[string]fileState {
+	state := map[string]fileState{}
+
+	for _, f := range files {
+		info, err := os.Stat(f)
+		if err != nil {
+			state[f] = fileState{}
+			continue
+		}
+		state[f] = fileState{modTime: info.ModTime(), size: info.Size(), exists: true}
+	}
+
+	return state
+}
+
+func filesChanged(before, after map[string]fileState) bool {
+	if len(before) != len(after) {
+		return true
+	}
+
+	for f, s := range before {
+		a, ok := after[f]
+		if !ok || a.exists != s.exists || a.size != s.size || !a.modTime.Equal(s.modTime) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func scaleSize(size int, scale float64) int {
+	scaled := int(float64(size) * scale)
+	if scaled < 1 {
+		return 1
+	}
+
+	return scaled
+}
@@ -0,0 +1,173 @@
+package world
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+
+	"github.com/dannyroes/raytrace/material"
+)
+
+// DefaultAdaptiveThreshold is the standard error accepted when
+// AdaptiveSampling.Threshold is not set, about a quarter of an 8 bit step.
+const DefaultAdaptiveThreshold = 0.001
+
+// AdaptiveSampling spends samples where they are needed instead of taking
+// Supersample x Supersample for every pixel. Each pixel starts with
+// MinSamples and takes MinSamples more at a time until the standard error of
+// its mean colour falls below Threshold on every channel, or it reaches
+// MaxSamples.
+type AdaptiveSampling struct {
+	MinSamples int
+	MaxSamples int
+	Threshold  float64
+}
+
+func (a AdaptiveSampling) Validate() error {
+	if a.MinSamples < 1 || a.MaxSamples < a.MinSamples {
+		return fmt.Errorf("invalid adaptive samples %d to %d", a.MinSamples, a.MaxSamples)
+	}
+	if a.Threshold < 0 {
+		return fmt.Errorf("invalid adaptive threshold %g", a.Threshold)
+	}
+
+	return nil
+}
+
+func (a AdaptiveSampling) String() string {
+	return fmt.Sprintf("%d-%d samples, threshold %g", a.MinSamples, a.MaxSamples, a.threshold())
+}
+
+func (a AdaptiveSampling) threshold() float64 {
+	if a.Threshold > 0 {
+		return a.Threshold
+	}
+
+	return DefaultAdaptiveThreshold
+}
+
+// offsets returns MaxSamples positions within a pixel. They are cells of a
+// square grid visited in a fixed random order, so that any leading run of
+// them is spread over the whole pixel.
+func (a AdaptiveSampling) offsets() [][2]float64 {
+	n := int(math.Ceil(math.Sqrt(float64(a.MaxSamples))))
+	order := rand.New(rand.NewSource(1)).Perm(n * n)
+
+	offsets := make([][2]float64, a.MaxSamples)
+	for i := range offsets {
+		cell := order[i]
+		offsets[i] = [2]float64{(float64(cell%n) + 0.5) / float64(n), (float64(cell/n) + 0.5) / float64(n)}
+	}
+
+	return offsets
+}
+
+// SampleCounts records how many samples an adaptive render took for each
+// pixel, row by row.
+type SampleCounts struct {
+	Width  int
+	Height int
+	Counts []int
+}
+
+func (s SampleCounts) At(x, y int) int {
+	return s.Counts[y*s.Width+x]
+}
+
+func (s SampleCounts) Total() int {
+	total := 0
+	for _, n := range s.Counts {
+		total += n
+	}
+
+	return total
+}
+
+// Image draws the counts as a heat map running from black for no samples to
+// red for max.
+func (s SampleCounts) Image(max int) CanvasType {
+	image := Canvas(s.Width, s.Height)
+	for y := 0; y < s.Height; y++ {
+		for x := 0; x < s.Width; x++ {
+			image.WritePixel(x, y, heatColour(float64(s.At(x, y))/float64(max)))
+		}
+	}
+
+	return image
+}
+
+// RenderAdaptive renders the world at the output size using the camera's
+// Adaptive settings, returning the number of samples each pixel took along
+// with the image. Samples are placed by the camera's Sampler if it has one.
+func (c *CameraType) RenderAdaptive(ctx context.Context, w WorldType) (CanvasType, SampleCounts, error) {
+	err := c.checkRegion()
+	if err != nil {
+		return CanvasType{}, SampleCounts{}, err
+	}
+
+	width, height := c.ImageSize()
+	counts := SampleCounts{Width: width, Height: height, Counts: make([]int, width*height)}
+	image := Canvas(width, height)
+
+	if c.Adaptive == nil {
+		return image, counts, fmt.Errorf("camera has no adaptive sampling settings")
+	}
+	a := *c.Adaptive
+	err = a.Validate()
+	if err != nil {
+		return image, counts, err
+	}
+	offsets := a.offsets()
+
+	// Each pixel belongs to one tile so the workers never share a count.
+	err = c.renderImage(ctx, &w, image, width, height, pass{sample: func(w *WorldType, x, y int) material.ColourTuple {
+		colour, n := c.adaptivePixel(w, x, y, a, offsets)
+		counts.Counts[y*counts.Width+x] = n
+		return colour
+	}}, nil)
+
+	return image, counts, err
+}
+
+// adaptivePixel samples x, y in batches of MinSamples until the pixel has
+// converged, returning its colour and the number of samples taken.
+func (c *CameraType) adaptivePixel(w *WorldType, x, y int, a AdaptiveSampling, offsets [][2]float64) (material.ColourTuple, int) {
+	var sum, sumSquares [3]float64
+	n := 0
+
+	for n < a.MaxSamples {
+		batch := a.MinSamples
+		if n+batch > a.MaxSamples {
+			batch = a.MaxSamples - n
+		}
+
+		for i := n; i < n+batch; i++ {
+			colour := c.sampleAt(w, x, y, i, offsets)
+			for i, v := range [3]float64{colour.Red(), colour.Green(), colour.Blue()} {
+				sum[i] += v
+				sumSquares[i] += v * v
+			}
+		}
+		n += batch
+
+		if n > 1 && standardError(sum, sumSquares, n) <= a.threshold() {
+			break
+		}
+	}
+
+	return material.Colour(sum[0], sum[1], sum[2]).Div(float64(n)), n
+}
+
+// standardError returns the largest standard error of the mean of the three
+// channels from their sums and sums of squares over n samples.
+func standardError(sum, sumSquares [3]float64, n int) float64 {
+	worst := 0.0
+	for i := range sum {
+		mean := sum[i] / float64(n)
+		variance := (sumSquares[i] - float64(n)*mean*mean) / float64(n-1)
+		worst = math.Max(worst, math.Sqrt(math.Max(variance, 0)/float64(n)))
+	}
+
+	return worst
+}
@@ -0,0 +1,127 @@
+package world
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dannyroes/raytrace/material"
+)
+
+func TestRenderAdaptiveFlatImage(t *testing.T) {
+	c, _ := traceTestScene()
+	c.Adaptive = &AdaptiveSampling{MinSamples: 4, MaxSamples: 16}
+
+	image, counts, err := c.RenderAdaptive(context.Background(), World())
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	if counts.Total() != 4*11*11 {
+		t.Errorf("Expected the minimum samples for an empty world, received %d", counts.Total())
+	}
+
+	if image.Pixel(5, 5) != material.Colour(0, 0, 0) {
+		t.Errorf("Expected black, received %+v", image.Pixel(5, 5))
+	}
+}
+
+func TestRenderAdaptiveRefinesEdges(t *testing.T) {
+	c, w := traceTestScene()
+	c.HSize, c.VSize = 21, 21
+	c.CalcPixelSize()
+	c.Adaptive = &AdaptiveSampling{MinSamples: 4, MaxSamples: 16}
+
+	image, counts, err := c.RenderAdaptive(context.Background(), w)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	maxed := 0
+	for _, n := range counts.Counts {
+		if n < 4 || n > 16 || n%4 != 0 {
+			t.Fatalf("Expected sample counts in batches of 4 up to 16, received %d", n)
+		}
+		if n == 16 {
+			maxed++
+		}
+	}
+
+	if maxed == 0 || counts.Total() >= 16*21*21 {
+		t.Errorf("Expected only some pixels to need every sample, %d of %d did", maxed, 21*21)
+	}
+
+	// With every sample taken the pixel matches a 4x supersampled render.
+	c.Adaptive = nil
+	c.Supersample = 4
+	expected := c.Render(w)
+
+	for x := 0; x < c.HSize; x++ {
+		for y := 0; y < c.VSize; y++ {
+			if counts.At(x, y) == 16 && !material.ColourEqual(image.Pixel(x, y), expected.Pixel(x, y)) {
+				t.Fatalf("Pixel %d,%d mismatch expected %+v received %+v", x, y, expected.Pixel(x, y), image.Pixel(x, y))
+			}
+		}
+	}
+}
+
+func TestRenderContextUsesAdaptive(t *testing.T) {
+	c, w := traceTestScene()
+	c.Supersample = 3
+	c.Adaptive = &AdaptiveSampling{MinSamples: 2, MaxSamples: 4}
+	w.Stats = &RayStats{}
+
+	image := c.Render(w)
+	if image.Width != 11 || image.Height != 11 {
+		t.Errorf("Size mismatch expected 11x11 received %dx%d", image.Width, image.Height)
+	}
+
+	if w.Stats.Primary < 2*11*11 || w.Stats.Primary > 4*11*11 {
+		t.Errorf("Expected between 2 and 4 samples per pixel, received %d rays", w.Stats.Primary)
+	}
+}
+
+func TestAdaptiveSamplingValidate(t *testing.T) {
+	tests := []struct {
+		a     AdaptiveSampling
+		valid bool
+	}{
+		{AdaptiveSampling{MinSamples: 4, MaxSamples: 64}, true},
+		{AdaptiveSampling{MinSamples: 1, MaxSamples: 1, Threshold: 0.01}, true},
+		{AdaptiveSampling{MinSamples: 0, MaxSamples: 4}, false},
+		{AdaptiveSampling{MinSamples: 8, MaxSamples: 4}, false},
+		{AdaptiveSampling{MinSamples: 4, MaxSamples: 8, Threshold: -1}, false},
+	}
+
+	for _, test := range tests {
+		err := test.a.Validate()
+		if (err == nil) != test.valid {
+			t.Errorf("%+v: expected valid %t, received %v", test.a, test.valid, err)
+		}
+	}
+}
+
+func TestAdaptiveOffsetsAreDistinct(t *testing.T) {
+	offsets := AdaptiveSampling{MinSamples: 1, MaxSamples: 10}.offsets()
+
+	if len(offsets) != 10 {
+		t.Fatalf("Offset count mismatch expected %d received %d", 10, len(offsets))
+	}
+
+	seen := map[[2]float64]bool{}
+	for _, o := range offsets {
+		if seen[o] || o[0] <= 0 || o[0] >= 1 || o[1] <= 0 || o[1] >= 1 {
+			t.Errorf("Expected distinct offsets inside the pixel, received %v", offsets)
+			break
+		}
+		seen[o] = true
+	}
+}
+
+func TestSampleCountsImage(t *testing.T) {
+	counts := SampleCounts{Width: 2, Height: 1, Counts: []int{0, 8}}
+	image := counts.Image(8)
+
+	if image.Pixel(0, 0) != material.Colour(0, 0, 0) || image.Pixel(1, 0) != material.Colour(1, 0, 0) {
+		t.Errorf("Expected black then red, received %+v and %+v", image.Pixel(0, 0), image.Pixel(1, 0))
+	}
+}
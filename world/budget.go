@@ -0,0 +1,129 @@
+package world
+
+import (
+	"context"
+	"math"
+	"time"
+)
+
+// budgetProbeScale is how much smaller than the image the probe renders that
+// estimate its cost are, along each axis.
+const budgetProbeScale = 16
+
+// MaxBudgetSupersample is the most samples per pixel, along each axis, that
+// RenderBudget plans for.
+const MaxBudgetSupersample = 8
+
+// BudgetPlan is what RenderBudget chose to fit its budget.
+type BudgetPlan struct {
+	MaxDepth    int
+	Supersample int
+	// PassCost is the estimated time to take one sample for every pixel at
+	// MaxDepth.
+	PassCost time.Duration
+}
+
+// RenderBudget renders the world in about the given time. A quick render at
+// a sixteenth of the size estimates how long one sample per pixel takes,
+// lowering MaxDepth until that fits in the time left. Supersample is then
+// chosen so every sample could be taken in the budget and the image is
+// refined with RenderProgressive until it is finished or the budget runs
+// out, returning the best image so far either way. The camera itself is not
+// changed. Adaptive sampling, filters and checkpoints are not used.
+//
+// The budget can be overrun by the time the workers take to finish the row
+// of a tile they are on when it expires.
+func (c *CameraType) RenderBudget(ctx context.Context, w WorldType, budget time.Duration) (CanvasType, BudgetPlan, error) {
+	deadline, cancel := context.WithTimeout(ctx, budget)
+	defer cancel()
+
+	start := time.Now()
+	remaining := func() time.Duration {
+		return budget - time.Since(start)
+	}
+
+	plan := BudgetPlan{MaxDepth: c.MaxDepth, Supersample: 1}
+
+	var probe CanvasType
+	for {
+		var err error
+		probe, plan.PassCost, err = c.probeCost(deadline, w, plan.MaxDepth)
+		if err != nil {
+			width, height := c.ImageSize()
+			return scaleNearest(probe, width, height), plan, budgetErr(ctx, err)
+		}
+
+		if plan.PassCost <= remaining() || plan.MaxDepth <= 0 {
+			break
+		}
+		plan.MaxDepth--
+	}
+
+	if plan.PassCost > 0 {
+		n := int(math.Sqrt(float64(remaining()) / float64(plan.PassCost)))
+		plan.Supersample = int(math.Max(1, math.Min(MaxBudgetSupersample, float64(n))))
+	}
+
+	render := *c
+	render.MaxDepth = plan.MaxDepth
+	render.Supersample = plan.Supersample
+	render.Adaptive = nil
+	render.Filter = nil
+	render.Checkpoint = nil
+
+	image, err := render.RenderProgressive(deadline, w, 0, nil)
+	return image, plan, budgetErr(ctx, err)
+}
+
+// budgetErr drops the error from the budget running out, keeping any from
+// ctx itself.
+func budgetErr(ctx context.Context, err error) error {
+	if err == context.DeadlineExceeded && ctx.Err() == nil {
+		return nil
+	}
+
+	return err
+}
+
+// probeCost renders the image, or its Region, at a fraction of its size
+// with one sample per pixel and the given depth, returning the probe image
+// and the time the full image would take at the same rate.
+func (c *CameraType) probeCost(ctx context.Context, w WorldType, depth int) (CanvasType, time.Duration, error) {
+	probe := *c
+	probe.HSize = int(math.Max(1, float64(c.HSize/budgetProbeScale)))
+	probe.VSize = int(math.Max(1, float64(c.VSize/budgetProbeScale)))
+	probe.CalcPixelSize()
+	if c.Region != nil {
+		region := c.Region.Scale(c.HSize, c.VSize, probe.HSize, probe.VSize)
+		probe.Region = &region
+	}
+	probe.MaxDepth = depth
+	probe.Supersample = 1
+	probe.Sampler = nil
+	probe.Adaptive = nil
+	probe.Filter = nil
+	probe.Checkpoint = nil
+	probe.Observer = nil
+
+	start := time.Now()
+	image, err := probe.RenderContext(ctx, w)
+	elapsed := time.Since(start)
+
+	width, height := c.ImageSize()
+	probeWidth, probeHeight := probe.ImageSize()
+	scale := float64(width*height) / float64(probeWidth*probeHeight)
+	return image, time.Duration(float64(elapsed) * scale), err
+}
+
+// scaleNearest resizes the image to width by height, each pixel taking the
+// colour of the nearest one in the original.
+func scaleNearest(image CanvasType, width, height int) CanvasType {
+	scaled := Canvas(width, height)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			scaled.WritePixel(x, y, image.Pixel(x*image.Width/width, y*image.Height/height))
+		}
+	}
+
+	return scaled
+}
@@ -0,0 +1,84 @@
+package world
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dannyroes/raytrace/material"
+)
+
+func TestRenderBudgetFinishes(t *testing.T) {
+	c, w := traceTestScene()
+	c.HSize, c.VSize = 6, 4
+	c.Supersample = 3
+	c.CalcPixelSize()
+
+	image, plan, err := c.RenderBudget(context.Background(), w, time.Minute)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	if plan.MaxDepth != c.MaxDepth || plan.Supersample != MaxBudgetSupersample {
+		t.Errorf("Expected a generous budget to keep depth %d and take %d samples, received %+v", c.MaxDepth, MaxBudgetSupersample, plan)
+	}
+	if c.Supersample != 3 {
+		t.Errorf("Expected the camera unchanged, received supersample %d", c.Supersample)
+	}
+
+	c.Supersample = plan.Supersample
+	expected := c.Render(w)
+	for x := 0; x < c.HSize; x++ {
+		for y := 0; y < c.VSize; y++ {
+			if !material.ColourEqual(image.Pixel(x, y), expected.Pixel(x, y)) {
+				t.Fatalf("Pixel %d,%d mismatch expected %+v received %+v", x, y, expected.Pixel(x, y), image.Pixel(x, y))
+			}
+		}
+	}
+}
+
+func TestRenderBudgetExpires(t *testing.T) {
+	c, w := traceTestScene()
+	c.HSize, c.VSize = 200, 150
+	c.CalcPixelSize()
+
+	budget := 50 * time.Millisecond
+	start := time.Now()
+	image, plan, err := c.RenderBudget(context.Background(), w, budget)
+	elapsed := time.Since(start)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	if image.Width != 200 || image.Height != 150 {
+		t.Errorf("Size mismatch expected 200x150 received %dx%d", image.Width, image.Height)
+	}
+	if plan.Supersample < 1 || plan.MaxDepth > c.MaxDepth {
+		t.Errorf("Unexpected plan %+v", plan)
+	}
+	if elapsed > budget+time.Second {
+		t.Errorf("Expected the render to stop near its budget of %v, took %v", budget, elapsed)
+	}
+}
+
+func TestRenderBudgetCancelled(t *testing.T) {
+	c, w := traceTestScene()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, _, err := c.RenderBudget(ctx, w, time.Minute)
+	if err != context.Canceled {
+		t.Errorf("Expected context.Canceled, received %v", err)
+	}
+}
+
+func TestScaleNearest(t *testing.T) {
+	image := Canvas(2, 1)
+	image.WritePixel(1, 0, material.Colour(1, 0, 0))
+
+	scaled := scaleNearest(image, 4, 2)
+	if scaled.Pixel(1, 1) != image.Pixel(0, 0) || scaled.Pixel(2, 0) != image.Pixel(1, 0) || scaled.Pixel(3, 1) != image.Pixel(1, 0) {
+		t.Errorf("Unexpected scaled image %+v", scaled)
+	}
+}
@@ -1,15 +1,17 @@
 package world
 
 import (
-	"fmt"
+	"context"
+	"errors"
 	"math"
 	"runtime"
 	"sync"
+	"sync/atomic"
 	"time"
 
-	movingaverage "github.com/RobinUS2/golang-moving-average"
 	"github.com/dannyroes/raytrace/data"
 	"github.com/dannyroes/raytrace/material"
+	"github.com/dannyroes/raytrace/sampler"
 )
 
 const MaxReflect int = 5
@@ -21,14 +23,33 @@ type CameraType struct {
 	FieldOfView float64
 	Transform   data.Matrix
 	PixelSize   float64
-	Verbose     bool
-	halfWidth   float64
-	halfHeight  float64
+	Distortion  *Distortion
+	Adaptive    *AdaptiveSampling
+	// Sampler, if set, places each sample within its pixel in place of
+	// the regular grid Supersample would use.
+	Sampler sampler.Sampler
+	Filter  *Filter
+	// Region, if set, limits the render to that part of the frame.
+	Region *Region
+	// Checkpoint, if set, records finished tiles so that the render can be
+	// resumed.
+	Checkpoint *Checkpoint
+	Workers    int
+	TileSize   int
+	TileOrder  TileOrder
+	MaxDepth   int
+	// Observer, if set, is told as the render starts, as each tile
+	// finishes, every ProgressInterval and when the render ends.
+	Observer         RenderObserver
+	ProgressInterval time.Duration
+	halfWidth        float64
+	halfHeight       float64
 }
 
 func Camera(hsize, vsize int, fieldOfView float64) *CameraType {
 	c := &CameraType{HSize: hsize, VSize: vsize, FieldOfView: fieldOfView, Transform: data.IdentityMatrix()}
 	c.Supersample = 1
+	c.MaxDepth = MaxReflect
 	c.CalcPixelSize()
 	return c
 }
@@ -49,12 +70,26 @@ func (c *CameraType) CalcPixelSize() {
 }
 
 func (c *CameraType) RayForPixel(x, y int) data.RayType {
-	xOffset := (float64(x) + 0.5) * c.PixelSize
-	yOffset := (float64(y) + 0.5) * c.PixelSize
+	return c.RayForSample(float64(x)+0.5, float64(y)+0.5)
+}
+
+// RayForSample returns the ray through a point on the image, measured in
+// pixels from the top left corner, so that the centre of pixel 0, 0 is at
+// 0.5, 0.5.
+func (c *CameraType) RayForSample(px, py float64) data.RayType {
+	xOffset := px * c.PixelSize
+	yOffset := py * c.PixelSize
 
 	worldX := c.halfWidth - xOffset
 	worldY := c.halfHeight - yOffset
 
+	if c.Distortion != nil {
+		// Distortion works with x to the right and y down, the opposite
+		// of camera space.
+		ux, uy := c.Distortion.Undistort(-worldX, -worldY)
+		worldX, worldY = -ux, -uy
+	}
+
 	pixel := c.Transform.Invert().MultiplyTuple(data.Point(worldX, worldY, -1))
 	origin := c.Transform.Invert().MultiplyTuple(data.Point(0, 0, 0))
 
@@ -64,125 +99,336 @@ func (c *CameraType) RayForPixel(x, y int) data.RayType {
 }
 
 func (c *CameraType) Render(w WorldType) CanvasType {
-	var image CanvasType
+	image, _ := c.RenderContext(context.Background(), w)
+	return image
+}
 
-	if c.Supersample > 1 {
-		c.HSize = c.HSize * c.Supersample
-		c.VSize = c.VSize * c.Supersample
-		c.CalcPixelSize()
+// RenderContext renders the world like Render but stops at the next row of
+// its tiles once ctx is cancelled, returning the partial image along with
+// ctx.Err(). Unfinished tiles are left out.
+// The image is split into tiles of TileSize pixels, handed out in TileOrder
+// to Workers goroutines. Progress is reported to Observer if it is set.
+//
+// Each pixel averages Supersample x Supersample samples, on a regular grid
+// or placed by Sampler if it is set. They are added up as each pixel is
+// rendered so only the final image is ever held in memory. When Adaptive is
+// set it is used in place of Supersample, see RenderAdaptive, and with a
+// Filter the samples are weighted by it, see Filter. With a Region the image
+// is just that part of the frame.
+func (c *CameraType) RenderContext(ctx context.Context, w WorldType) (CanvasType, error) {
+	err := c.checkRegion()
+	if err != nil {
+		return CanvasType{}, err
 	}
-	c.log("Rendering width %d; height %d\n", c.HSize, c.VSize)
-	image = Canvas(c.HSize, c.VSize)
 
-	in := make(chan PixelJob)
-	out := make(chan PixelColour)
+	if c.Adaptive != nil {
+		image, _, err := c.RenderAdaptive(ctx, w)
+		return image, err
+	}
 
-	wg := &sync.WaitGroup{}
+	if c.Filter != nil {
+		return c.renderFiltered(ctx, w)
+	}
 
-	for x := 0; x < runtime.NumCPU()-1; x++ {
-		wg.Add(1)
-		go renderPixel(in, out, wg)
+	width, height := c.ImageSize()
+	image := Canvas(width, height)
+	err = c.renderImage(ctx, &w, image, width, height, c.supersamplePass(), nil)
+
+	return image, err
+}
+
+// RenderTo renders like RenderContext but hands each tile to out as it is
+// finished instead of building the image in memory, so memory use depends
+// on the tile size rather than the image size. Adaptive sampling, filters
+// and checkpoints need the whole image and cannot be used.
+func (c *CameraType) RenderTo(ctx context.Context, w WorldType, out TileWriter) error {
+	if c.Adaptive != nil || c.Filter != nil || c.Checkpoint != nil {
+		return errors.New("adaptive sampling, filters and checkpoints need the whole image in memory")
 	}
 
-	go func() {
-		wg.Wait()
-		close(out)
-	}()
+	err := c.checkRegion()
+	if err != nil {
+		return err
+	}
 
-	c.log("Beginning render\n")
+	width, height := c.ImageSize()
+	return c.renderImage(ctx, &w, out, width, height, c.supersamplePass(), nil)
+}
 
-	p := 0
-	t := time.Now()
+// supersamplePass averages Supersample x Supersample samples per pixel.
+func (c *CameraType) supersamplePass() pass {
+	offsets := gridOffsets(c.Supersample)
+	n := c.samplesPerPixel()
 
-	go func() {
-		for y := 0; y < c.VSize; y++ {
-			for x := 0; x < c.HSize; x++ {
-				in <- PixelJob{x, y, c, w}
+	return pass{sample: func(w *WorldType, x, y int) material.ColourTuple {
+		colour := material.Colour(0, 0, 0)
+		for i := 0; i < n; i++ {
+			colour = colour.Add(c.sampleAt(w, x, y, i, offsets))
+		}
+		return colour.Div(float64(n))
+	}}
+}
+
+// renderImage runs the pass over every tile of a width by height image,
+// handing each to out and telling Observer about it as it finishes. If f is
+// set the samples of each tile are also added to it. Tiles held by the
+// camera's Checkpoint are passed on without rendering them again. The
+// render stops early if ctx is cancelled or out fails.
+func (c *CameraType) renderImage(ctx context.Context, w *WorldType, out TileWriter, width, height int, ps pass, f *film) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	tiles := Tiles(width, height, c.TileSize, c.TileOrder)
+
+	c.notify(func(o RenderObserver) {
+		o.RenderStarted(RenderStarted{width, height, c.Supersample, len(tiles), c.workers()})
+	})
+
+	p := newProgress(c, width*height)
+	var err error
+
+	if c.Checkpoint != nil {
+		var finished []TileResult
+		finished, tiles = c.Checkpoint.resume(width, height, tiles, f)
+
+		for _, result := range finished {
+			err = out.WriteTile(result)
+			if err != nil {
+				tiles = nil
+				break
 			}
+
+			c.notify(func(o RenderObserver) {
+				o.TileFinished(TileFinished{result.Tile, result.Pixels})
+			})
+			p.done += len(result.Pixels)
+		}
+		p.lastDone = p.done
+	}
+
+	for result := range c.runPass(ctx, w, tiles, ps) {
+		if err != nil {
+			continue
+		}
+
+		err = out.WriteTile(result)
+		if err != nil {
+			cancel()
+			continue
 		}
-		close(in)
-	}()
 
-	lastUpdate := time.Now()
-	totalPixels := c.HSize * c.VSize
-	lastPixels := 0
-	ma := movingaverage.New(30)
-
-	for pixel := range out {
-		image.WritePixel(pixel.x, pixel.y, pixel.c)
-		p++
-		if c.Verbose && time.Since(lastUpdate) > 1*time.Second {
-			lastUpdate = time.Now()
-			passed := time.Since(t)
-			pixelsSince := p - lastPixels
-			ma.Add(float64(pixelsSince))
-			lastPixels = p
-			remain := time.Duration(float64(totalPixels-p)/ma.Avg()) * time.Second
-			percent := float64(p) / float64(totalPixels) * 100
-
-			c.log("Elapsed: %v - %.2f%% complete, estimate remaining: %-10v\r", passed.Truncate(time.Second), percent, remain.Truncate(time.Second))
+		if f != nil {
+			f.add(result.samples)
 		}
+		if c.Checkpoint != nil {
+			c.Checkpoint.record(result)
+		}
+
+		c.notify(func(o RenderObserver) {
+			o.TileFinished(TileFinished{result.Tile, result.Pixels})
+		})
+		p.add(len(result.Pixels))
 	}
 
-	duration := time.Since(t)
-	c.log("Rendered %d pixels in %-40v\n", p, duration)
+	if err == nil {
+		err = ctx.Err()
+	}
 
-	if c.Supersample > 1 {
-		c.HSize = c.HSize / c.Supersample
-		c.VSize = c.VSize / c.Supersample
-		c.log("Downsampling to %dx%d\n", c.HSize, c.VSize)
-		image = downsample(image, c.HSize, c.VSize)
+	if c.Checkpoint != nil && err != nil {
+		c.Checkpoint.save()
 	}
-	return image
+
+	p.finish(w, err)
+	return err
 }
 
-func (c *CameraType) log(msg string, items ...interface{}) {
-	if c.Verbose {
-		fmt.Printf(msg, items...)
+func (c *CameraType) workers() int {
+	if c.Workers > 0 {
+		return c.Workers
 	}
+
+	if runtime.NumCPU() > 1 {
+		return runtime.NumCPU() - 1
+	}
+
+	return 1
 }
 
-func downsample(image CanvasType, width, height int) CanvasType {
-	factor := image.Width / width
+func (c *CameraType) progressInterval() time.Duration {
+	if c.ProgressInterval > 0 {
+		return c.ProgressInterval
+	}
 
-	newImage := Canvas(width, height)
+	return DefaultProgressInterval
+}
 
-	for y := 0; y < height; y++ {
-		for x := 0; x < width; x++ {
-			c := material.Colour(0, 0, 0)
-			for iy := 0; iy < factor; iy++ {
-				for ix := 0; ix < factor; ix++ {
-					c = c.Add(image.Pixel(x*factor+ix, y*factor+iy))
-				}
+func (c *CameraType) notify(fn func(o RenderObserver)) {
+	if c.Observer != nil {
+		fn(c.Observer)
+	}
+}
+
+// TileResult holds the colours of a rendered tile, row by row.
+type TileResult struct {
+	Tile   Tile
+	Pixels []material.ColourTuple
+	// samples are kept for passes that filter them into a film.
+	samples []filmSample
+}
+
+// TileWriter receives the tiles of a render as they are finished.
+type TileWriter interface {
+	WriteTile(t TileResult) error
+}
+
+// WriteTile copies the tile onto the canvas.
+func (c CanvasType) WriteTile(t TileResult) error {
+	t.copyTo(c)
+	return nil
+}
+
+// discardTiles is a TileWriter for renders that build their image from the
+// samples in each tile instead.
+type discardTiles struct{}
+
+func (discardTiles) WriteTile(t TileResult) error {
+	return nil
+}
+
+func (r TileResult) copyTo(image CanvasType) {
+	for i, colour := range r.Pixels {
+		image.WritePixel(r.Tile.X+i%r.Tile.Width, r.Tile.Y+i/r.Tile.Width, colour)
+	}
+}
+
+// pass describes one sweep over the image. sample is called for each pixel
+// that include accepts, or every pixel if include is nil. A pass with
+// samples set calls it instead and keeps each sample in the TileResult, with
+// their average as the pixel's colour.
+type pass struct {
+	include func(x, y int) bool
+	sample  func(w *WorldType, x, y int) material.ColourTuple
+	samples func(w *WorldType, x, y int) []filmSample
+}
+
+// runPass renders the tiles on c.workers() goroutines. The channel returned
+// receives each tile as it is finished and is closed once they are all done
+// or ctx is cancelled. Pixels the pass skips are left black.
+func (c *CameraType) runPass(ctx context.Context, w *WorldType, tiles []Tile, p pass) <-chan TileResult {
+	in := make(chan Tile, len(tiles))
+	out := make(chan TileResult, c.workers())
+
+	for _, tile := range tiles {
+		in <- tile
+	}
+	close(in)
+
+	wg := &sync.WaitGroup{}
+
+	for x := 0; x < c.workers(); x++ {
+		wg.Add(1)
+		go renderTiles(ctx, w, in, out, wg, p)
+	}
+
+	go func() {
+		wg.Wait()
+		close(out)
+	}()
+
+	return out
+}
+
+// renderTiles renders tiles from in until it is empty or ctx is cancelled.
+// Every worker shares the one world.
+func renderTiles(ctx context.Context, w *WorldType, in <-chan Tile, out chan<- TileResult, wg *sync.WaitGroup, p pass) {
+	defer wg.Done()
+
+	for tile := range in {
+		if ctx.Err() != nil {
+			return
+		}
+
+		result := TileResult{Tile: tile, Pixels: make([]material.ColourTuple, tile.Width*tile.Height)}
+		for y := tile.Y; y < tile.Y+tile.Height; y++ {
+			// Checked every row too so slow tiles don't hold up a
+			// cancelled render, abandoning the tile.
+			if ctx.Err() != nil {
+				return
 			}
 
-			c = c.Div(float64(factor * factor))
-			newImage.WritePixel(x, y, c)
+			for x := tile.X; x < tile.X+tile.Width; x++ {
+				if p.include != nil && !p.include(x, y) {
+					continue
+				}
+
+				if p.samples == nil {
+					result.Pixels[(y-tile.Y)*tile.Width+x-tile.X] = p.sample(w, x, y)
+					continue
+				}
+
+				samples := p.samples(w, x, y)
+				result.samples = append(result.samples, samples...)
+				result.Pixels[(y-tile.Y)*tile.Width+x-tile.X] = averageSamples(samples)
+			}
 		}
+
+		out <- result
+	}
+}
+
+// samplePixel traces the ray through a point on the image, see RayForSample.
+// The point is measured from the camera's Region if it has one.
+func (c *CameraType) samplePixel(w *WorldType, px, py float64) material.ColourTuple {
+	if w.Stats != nil {
+		atomic.AddUint64(&w.Stats.Primary, 1)
 	}
 
-	return newImage
+	ox, oy := c.regionOrigin()
+	return w.ColourAt(c.RayForSample(px+float64(ox), py+float64(oy)), c.MaxDepth)
+}
+
+// sampleAt traces the i'th sample of pixel x, y, placed by Sampler if the
+// camera has one or at offsets[i] otherwise.
+func (c *CameraType) sampleAt(w *WorldType, x, y, i int, offsets [][2]float64) material.ColourTuple {
+	px, py := c.samplePosition(x, y, i, offsets)
+	return c.samplePixel(w, px, py)
 }
 
-type PixelJob struct {
-	x int
-	y int
-	c *CameraType
-	w WorldType
+// samplePosition returns where on the image the i'th sample of pixel x, y is
+// taken, see sampleAt. The Sampler is seeded with the pixel's place in the
+// full frame so a Region samples it the same as a full render.
+func (c *CameraType) samplePosition(x, y, i int, offsets [][2]float64) (float64, float64) {
+	if c.Sampler != nil {
+		ox, oy := c.regionOrigin()
+		u, v := c.Sampler.Sample2D(x+ox, y+oy, i, 0)
+		return float64(x) + u, float64(y) + v
+	}
+
+	return float64(x) + offsets[i][0], float64(y) + offsets[i][1]
 }
 
-type PixelColour struct {
-	x int
-	y int
-	c material.ColourTuple
+// gridOffsets returns the centres of an n by n grid over a pixel, row by
+// row, the positions of the samples Supersample takes.
+func gridOffsets(n int) [][2]float64 {
+	if n < 1 {
+		n = 1
+	}
+
+	offsets := make([][2]float64, 0, n*n)
+	for j := 0; j < n; j++ {
+		for i := 0; i < n; i++ {
+			offsets = append(offsets, [2]float64{(float64(i) + 0.5) / float64(n), (float64(j) + 0.5) / float64(n)})
+		}
+	}
+
+	return offsets
 }
 
-func renderPixel(c <-chan PixelJob, out chan<- PixelColour, wg *sync.WaitGroup) {
-	for p := range c {
-		ray := p.c.RayForPixel(p.x, p.y)
-		colour := p.w.ColourAt(ray, MaxReflect)
-		out <- PixelColour{p.x, p.y, colour}
+// samplesPerPixel is the number of samples Supersample asks for.
+func (c *CameraType) samplesPerPixel() int {
+	if c.Supersample > 1 {
+		return c.Supersample * c.Supersample
 	}
 
-	wg.Done()
+	return 1
 }
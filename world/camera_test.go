@@ -1,11 +1,13 @@
 package world
 
 import (
+	"context"
 	"math"
 	"testing"
 
 	"github.com/dannyroes/raytrace/data"
 	"github.com/dannyroes/raytrace/material"
+	"github.com/dannyroes/raytrace/sampler"
 )
 
 func TestCamera(t *testing.T) {
@@ -113,3 +115,93 @@ func TestRender(t *testing.T) {
 		t.Errorf("Ray Direction mismatch expected %f received %f", expected, pixel)
 	}
 }
+
+func TestRenderContextCancelled(t *testing.T) {
+	w := DefaultWorld()
+	c := Camera(11, 11, math.Pi/2)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	image, err := c.RenderContext(ctx, w)
+	if err != context.Canceled {
+		t.Errorf("Expected %v received %v", context.Canceled, err)
+	}
+
+	if image.Width != 11 || image.Height != 11 {
+		t.Errorf("Canvas size mismatch expected 11x11 received %dx%d", image.Width, image.Height)
+	}
+}
+
+func TestRenderWithSamplerIndependentOfWorkers(t *testing.T) {
+	for _, s := range []sampler.Sampler{sampler.Uniform(1), sampler.Stratified(4, 1), sampler.Halton(1), sampler.Sobol(1)} {
+		c, w := traceTestScene()
+		c.Supersample = 2
+		c.Sampler = s
+		c.Workers = 1
+		expected := c.Render(w)
+
+		c.Workers = 4
+		c.TileSize = 3
+		c.TileOrder = TileHilbert
+		image := c.Render(w)
+
+		if image.Width != 11 || image.Height != 11 {
+			t.Fatalf("%s: size mismatch expected 11x11 received %dx%d", s, image.Width, image.Height)
+		}
+
+		for x := 0; x < c.HSize; x++ {
+			for y := 0; y < c.VSize; y++ {
+				if image.Pixel(x, y) != expected.Pixel(x, y) {
+					t.Fatalf("%s: pixel %d,%d differs between worker counts: %+v and %+v", s, x, y, expected.Pixel(x, y), image.Pixel(x, y))
+				}
+			}
+		}
+	}
+}
+
+func TestRenderWithSamplerJittersSamples(t *testing.T) {
+	c, w := traceTestScene()
+	centred := c.Render(w)
+
+	c.Sampler = sampler.Uniform(1)
+	jittered := c.Render(w)
+
+	differing := 0
+	for x := 0; x < c.HSize; x++ {
+		for y := 0; y < c.VSize; y++ {
+			if !material.ColourEqual(centred.Pixel(x, y), jittered.Pixel(x, y)) {
+				differing++
+			}
+		}
+	}
+
+	if differing == 0 {
+		t.Error("Expected samples away from the pixel centres to change the image")
+	}
+}
+
+func TestRenderSupersampleMatchesUpscaledAverage(t *testing.T) {
+	c, w := traceTestScene()
+	c.Supersample = 3
+	image := c.Render(w)
+
+	// The samples are those of a render at three times the size.
+	large, _ := traceTestScene()
+	large.HSize, large.VSize = 33, 33
+	large.CalcPixelSize()
+
+	for _, p := range [][2]int{{0, 0}, {5, 5}, {3, 8}, {10, 2}} {
+		expected := material.Colour(0, 0, 0)
+		for iy := 0; iy < 3; iy++ {
+			for ix := 0; ix < 3; ix++ {
+				expected = expected.Add(w.ColourAt(large.RayForPixel(p[0]*3+ix, p[1]*3+iy), large.MaxDepth))
+			}
+		}
+		expected = expected.Div(9)
+
+		if !material.ColourEqual(image.Pixel(p[0], p[1]), expected) {
+			t.Errorf("Pixel %v mismatch expected %+v received %+v", p, expected, image.Pixel(p[0], p[1]))
+		}
+	}
+}
@@ -38,6 +38,31 @@ func (c CanvasType) WritePixel(x, y int, colour material.ColourTuple) {
 	c.Pixels[x][y] = colour
 }
 
+// Draw copies src onto the canvas with its top left corner at x, y. Pixels
+// falling outside the canvas are skipped.
+func (c CanvasType) Draw(src CanvasType, x, y int) {
+	for sx := 0; sx < src.Width; sx++ {
+		if x+sx < 0 || x+sx >= c.Width {
+			continue
+		}
+		for sy := 0; sy < src.Height; sy++ {
+			if y+sy < 0 || y+sy >= c.Height {
+				continue
+			}
+			c.Pixels[x+sx][y+sy] = src.Pixels[sx][sy]
+		}
+	}
+}
+
+// Fill sets every pixel on the canvas to colour.
+func (c CanvasType) Fill(colour material.ColourTuple) {
+	for x := 0; x < c.Width; x++ {
+		for y := 0; y < c.Height; y++ {
+			c.Pixels[x][y] = colour
+		}
+	}
+}
+
 func (c CanvasType) ToPPM() string {
 	var pixels strings.Builder
 	var line strings.Builder
@@ -74,10 +99,18 @@ func (c CanvasType) ToPNG(filename string) error {
 	if err != nil {
 		return err
 	}
-	defer f.Close()
 
-	png.Encode(f, im)
-	return nil
+	err = png.Encode(f, im)
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+func (c CanvasType) ToPPMFile(filename string) error {
+	return os.WriteFile(filename, []byte(c.ToPPM()), 0644)
 }
 
 func writePixelValue(pixels, line *strings.Builder, value string) {
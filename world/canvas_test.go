@@ -1,6 +1,8 @@
 package world
 
 import (
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
@@ -110,3 +112,44 @@ func TestCanvasTrailingNewline(t *testing.T) {
 		t.Error("Expected trailing newline")
 	}
 }
+
+func TestCanvasToPPMFile(t *testing.T) {
+	c := Canvas(5, 3)
+	c.WritePixel(0, 0, material.Colour(1, 0, 0))
+
+	filename := filepath.Join(t.TempDir(), "canvas.ppm")
+	err := c.ToPPMFile(filename)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	contents, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	if string(contents) != c.ToPPM() {
+		t.Errorf("Expected '%s', received '%s'", c.ToPPM(), string(contents))
+	}
+}
+
+func TestCanvasDraw(t *testing.T) {
+	c := Canvas(5, 4)
+	src := Canvas(3, 3)
+	red := material.Colour(1, 0, 0)
+	src.Fill(red)
+
+	c.Draw(src, 3, -1)
+
+	for x := 0; x < c.Width; x++ {
+		for y := 0; y < c.Height; y++ {
+			expected := material.Black
+			if x >= 3 && y <= 1 {
+				expected = red
+			}
+			if !material.ColourEqual(c.Pixel(x, y), expected) {
+				t.Errorf("Pixel %d,%d expected %+v, received %+v", x, y, expected, c.Pixel(x, y))
+			}
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package world
+
+import (
+	"encoding/gob"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/dannyroes/raytrace/material"
+)
+
+// DefaultCheckpointInterval is how often a checkpoint is saved when
+// Checkpoint.Interval is not set.
+const DefaultCheckpointInterval = time.Minute
+
+// Checkpoint keeps the tiles a render has finished so that it can be
+// resumed after it is stopped. Set it on the camera and the render skips any
+// tile it already holds, records each tile as it finishes and saves itself
+// to Filename every Interval and when the render is cancelled.
+//
+// Key identifies the scene and render settings; a checkpoint is only loaded
+// for a render with the same key. Adaptive renders do not keep the sample
+// counts of resumed tiles, and progressive renders ignore checkpoints.
+type Checkpoint struct {
+	Filename string
+	Key      string
+	Interval time.Duration
+	width    int
+	height   int
+	tiles    map[Tile][]material.ColourTuple
+	film     [][4]int64
+	lastSave time.Time
+	err      error
+}
+
+// checkpointFile is the layout of a saved checkpoint.
+type checkpointFile struct {
+	Key    string
+	Width  int
+	Height int
+	Tiles  []TileResult
+	Film   [][4]int64
+}
+
+func NewCheckpoint(filename, key string, interval time.Duration) *Checkpoint {
+	return &Checkpoint{
+		Filename: filename,
+		Key:      key,
+		Interval: interval,
+		tiles:    map[Tile][]material.ColourTuple{},
+		lastSave: time.Now(),
+	}
+}
+
+// LoadCheckpoint reads the checkpoint saved in filename, refusing it if it
+// was written for a render with a different key.
+func LoadCheckpoint(filename, key string, interval time.Duration) (*Checkpoint, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var saved checkpointFile
+	err = gob.NewDecoder(f).Decode(&saved)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", filename, err)
+	}
+
+	if saved.Key != key {
+		return nil, fmt.Errorf("%s: checkpoint was written for a different scene or render settings", filename)
+	}
+
+	cp := NewCheckpoint(filename, key, interval)
+	cp.width, cp.height = saved.Width, saved.Height
+	cp.film = saved.Film
+	for _, t := range saved.Tiles {
+		cp.tiles[t.Tile] = t.Pixels
+	}
+
+	return cp, nil
+}
+
+// Finished returns the number of tiles recorded.
+func (cp *Checkpoint) Finished() int {
+	return len(cp.tiles)
+}
+
+// Err returns the first error met saving the checkpoint during a render.
+func (cp *Checkpoint) Err() error {
+	return cp.err
+}
+
+// Save writes the checkpoint to Filename, replacing it only once the new
+// copy is complete.
+func (cp *Checkpoint) Save() error {
+	saved := checkpointFile{Key: cp.Key, Width: cp.width, Height: cp.height, Film: cp.film}
+	for tile, pixels := range cp.tiles {
+		saved.Tiles = append(saved.Tiles, TileResult{Tile: tile, Pixels: pixels})
+	}
+
+	tmp := cp.Filename + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+
+	err = gob.NewEncoder(f).Encode(saved)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	cp.lastSave = time.Now()
+	return os.Rename(tmp, cp.Filename)
+}
+
+// Remove deletes the saved checkpoint, once the render it was for is done.
+func (cp *Checkpoint) Remove() error {
+	err := os.Remove(cp.Filename)
+	if os.IsNotExist(err) {
+		return nil
+	}
+
+	return err
+}
+
+func (cp *Checkpoint) interval() time.Duration {
+	if cp.Interval > 0 {
+		return cp.Interval
+	}
+
+	return DefaultCheckpointInterval
+}
+
+// resume returns the tiles already finished and those still to render. A
+// checkpoint for an image of another size is started afresh. If f is set it
+// is given the samples the finished tiles added to the film.
+func (cp *Checkpoint) resume(width, height int, tiles []Tile, f *film) ([]TileResult, []Tile) {
+	if cp.width != width || cp.height != height {
+		cp.width, cp.height = width, height
+		cp.tiles = map[Tile][]material.ColourTuple{}
+		cp.film = nil
+	}
+
+	if f != nil {
+		if len(cp.film) == len(f.sums) {
+			copy(f.sums, cp.film)
+		} else {
+			cp.tiles = map[Tile][]material.ColourTuple{}
+		}
+		cp.film = f.sums
+	}
+
+	var finished []TileResult
+	var remaining []Tile
+	for _, tile := range tiles {
+		if pixels, ok := cp.tiles[tile]; ok {
+			finished = append(finished, TileResult{Tile: tile, Pixels: pixels})
+		} else {
+			remaining = append(remaining, tile)
+		}
+	}
+
+	return finished, remaining
+}
+
+// record adds a finished tile, saving the checkpoint if Interval has passed.
+func (cp *Checkpoint) record(result TileResult) {
+	cp.tiles[result.Tile] = result.Pixels
+
+	if time.Since(cp.lastSave) >= cp.interval() {
+		cp.save()
+	}
+}
+
+// save is Save keeping the first error for Err.
+func (cp *Checkpoint) save() {
+	err := cp.Save()
+	if err != nil && cp.err == nil {
+		cp.err = err
+	}
+}
@@ -0,0 +1,108 @@
+package world
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// interruptedRender renders until a few tiles are done, leaving a checkpoint
+// in filename.
+func interruptedRender(t *testing.T, c *CameraType, w WorldType, filename string) {
+	ctx, cancel := context.WithCancel(context.Background())
+	c.Workers = 1
+	c.Checkpoint = NewCheckpoint(filename, "scene", time.Hour)
+	c.Observer = &recordingObserver{cancel: cancel}
+
+	_, err := c.RenderContext(ctx, w)
+	if err != context.Canceled {
+		t.Fatalf("Expected context.Canceled, received %v", err)
+	}
+	if c.Checkpoint.Err() != nil {
+		t.Fatalf("Unexpected error saving checkpoint %v", c.Checkpoint.Err())
+	}
+
+	c.Observer = nil
+	c.Checkpoint = nil
+}
+
+func TestCheckpointResume(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "render.checkpoint")
+
+	for _, filter := range []*Filter{nil, {Kind: FilterMitchell}} {
+		c, w := traceTestScene()
+		c.TileSize = 4
+		c.Supersample = 2
+		c.Filter = filter
+		expected := c.Render(w)
+
+		interruptedRender(t, c, w, filename)
+
+		cp, err := LoadCheckpoint(filename, "scene", time.Hour)
+		if err != nil {
+			t.Fatalf("Unexpected error %v", err)
+		}
+		if cp.Finished() == 0 || cp.Finished() >= 9 {
+			t.Fatalf("Expected some of the 9 tiles in the checkpoint, received %d", cp.Finished())
+		}
+
+		c.Checkpoint = cp
+		w.Stats = &RayStats{}
+		image, err := c.RenderContext(context.Background(), w)
+		if err != nil {
+			t.Fatalf("Unexpected error %v", err)
+		}
+
+		if w.Stats.Primary >= 11*11*4 {
+			t.Errorf("Expected the resumed render to skip finished tiles, received %d rays", w.Stats.Primary)
+		}
+
+		for x := 0; x < c.HSize; x++ {
+			for y := 0; y < c.VSize; y++ {
+				if image.Pixel(x, y) != expected.Pixel(x, y) {
+					t.Fatalf("Filter %v: pixel %d,%d mismatch expected %+v received %+v", filter, x, y, expected.Pixel(x, y), image.Pixel(x, y))
+				}
+			}
+		}
+	}
+}
+
+func TestCheckpointKeyMismatch(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "render.checkpoint")
+
+	c, w := traceTestScene()
+	c.TileSize = 4
+	interruptedRender(t, c, w, filename)
+
+	_, err := LoadCheckpoint(filename, "another scene", time.Hour)
+	if err == nil {
+		t.Error("Expected error loading a checkpoint for another scene")
+	}
+}
+
+func TestCheckpointSavesAtInterval(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "render.checkpoint")
+
+	c, w := traceTestScene()
+	c.TileSize = 4
+	c.Checkpoint = NewCheckpoint(filename, "scene", time.Nanosecond)
+	c.Render(w)
+
+	cp, err := LoadCheckpoint(filename, "scene", 0)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if cp.Finished() != 9 {
+		t.Errorf("Expected all 9 tiles saved, received %d", cp.Finished())
+	}
+
+	err = cp.Remove()
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if _, err := os.Stat(filename); !os.IsNotExist(err) {
+		t.Errorf("Expected checkpoint removed, received %v", err)
+	}
+}
@@ -0,0 +1,224 @@
+package world
+
+import (
+	"fmt"
+	"image"
+	"image/png"
+	"math"
+	"os"
+
+	"github.com/dannyroes/raytrace/material"
+)
+
+// ssimWindow is the side of the square windows SSIM is measured over, and
+// ssimStep how far apart neighbouring windows start.
+const (
+	ssimWindow = 8
+	ssimStep   = 4
+)
+
+type Comparison struct {
+	MaxError  float64
+	RMSE      float64
+	PSNR      float64
+	SSIM      float64
+	Differing int
+	Diff      CanvasType
+}
+
+func CanvasFromImage(im image.Image) CanvasType {
+	b := im.Bounds()
+	c := Canvas(b.Dx(), b.Dy())
+
+	for x := 0; x < c.Width; x++ {
+		for y := 0; y < c.Height; y++ {
+			r, g, bl, _ := im.At(b.Min.X+x, b.Min.Y+y).RGBA()
+			c.Pixels[x][y] = material.Colour(float64(r)/65535, float64(g)/65535, float64(bl)/65535)
+		}
+	}
+
+	return c
+}
+
+func LoadPNG(filename string) (CanvasType, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return CanvasType{}, err
+	}
+	defer f.Close()
+
+	im, err := png.Decode(f)
+	if err != nil {
+		return CanvasType{}, fmt.Errorf("%s: %v", filename, err)
+	}
+
+	return CanvasFromImage(im), nil
+}
+
+// Quantize returns the canvas as it would be read back after saving it to an
+// 8 bit image, so it can be compared with one.
+func (c CanvasType) Quantize() CanvasType {
+	return CanvasFromImage(c.ToImage())
+}
+
+// Compare measures how far b is from a. Colours are clamped to [0, 1] before
+// comparing. Diff is a false colour image running from black where the
+// canvases match through blue, green and yellow to red at the largest error.
+func Compare(a, b CanvasType) (Comparison, error) {
+	if a.Width != b.Width || a.Height != b.Height {
+		return Comparison{}, fmt.Errorf("image sizes differ: %dx%d and %dx%d", a.Width, a.Height, b.Width, b.Height)
+	}
+
+	res := Comparison{Diff: Canvas(a.Width, a.Height)}
+	errors := make([][]float64, a.Width)
+	sum := 0.0
+
+	for x := 0; x < a.Width; x++ {
+		errors[x] = make([]float64, a.Height)
+		for y := 0; y < a.Height; y++ {
+			pa := clampColour(a.Pixels[x][y])
+			pb := clampColour(b.Pixels[x][y])
+
+			dr := pa.Red() - pb.Red()
+			dg := pa.Green() - pb.Green()
+			db := pa.Blue() - pb.Blue()
+			sum += dr*dr + dg*dg + db*db
+
+			e := maxOf3(math.Abs(dr), math.Abs(dg), math.Abs(db))
+			errors[x][y] = e
+			if e > res.MaxError {
+				res.MaxError = e
+			}
+			if e > 0 {
+				res.Differing++
+			}
+		}
+	}
+
+	pixels := float64(a.Width * a.Height)
+	if pixels > 0 {
+		res.RMSE = math.Sqrt(sum / (pixels * 3))
+	}
+
+	if res.RMSE == 0 {
+		res.PSNR = math.Inf(1)
+	} else {
+		res.PSNR = 20 * math.Log10(1/res.RMSE)
+	}
+
+	res.SSIM = ssim(a, b)
+
+	for x := 0; x < a.Width; x++ {
+		for y := 0; y < a.Height; y++ {
+			if res.MaxError > 0 {
+				res.Diff.Pixels[x][y] = heatColour(errors[x][y] / res.MaxError)
+			}
+		}
+	}
+
+	return res, nil
+}
+
+// ssim is the mean structural similarity of the luminance of both canvases,
+// measured over overlapping square windows.
+func ssim(a, b CanvasType) float64 {
+	const c1 = 0.01 * 0.01
+	const c2 = 0.03 * 0.03
+
+	window := ssimWindow
+	if a.Width < window || a.Height < window {
+		window = int(math.Min(float64(a.Width), float64(a.Height)))
+	}
+	if window == 0 {
+		return 1
+	}
+	// Windows shrunk for a small image would otherwise leave pixels out.
+	step := ssimStep
+	if step > window {
+		step = window
+	}
+
+	total := 0.0
+	count := 0
+
+	for wx := 0; wx+window <= a.Width; wx += step {
+		for wy := 0; wy+window <= a.Height; wy += step {
+			var meanA, meanB float64
+			for x := wx; x < wx+window; x++ {
+				for y := wy; y < wy+window; y++ {
+					meanA += luminance(a.Pixels[x][y])
+					meanB += luminance(b.Pixels[x][y])
+				}
+			}
+
+			n := float64(window * window)
+			meanA /= n
+			meanB /= n
+
+			var varA, varB, cov float64
+			for x := wx; x < wx+window; x++ {
+				for y := wy; y < wy+window; y++ {
+					da := luminance(a.Pixels[x][y]) - meanA
+					db := luminance(b.Pixels[x][y]) - meanB
+					varA += da * da
+					varB += db * db
+					cov += da * db
+				}
+			}
+			// A single pixel window has no spread to correct for, and
+			// n - 1 would divide by zero.
+			norm := n - 1
+			if window < 2 {
+				norm = n
+			}
+			varA /= norm
+			varB /= norm
+			cov /= norm
+
+			total += ((2*meanA*meanB + c1) * (2*cov + c2)) / ((meanA*meanA + meanB*meanB + c1) * (varA + varB + c2))
+			count++
+		}
+	}
+
+	return total / float64(count)
+}
+
+func luminance(c material.ColourTuple) float64 {
+	c = clampColour(c)
+	return 0.2126*c.Red() + 0.7152*c.Green() + 0.0722*c.Blue()
+}
+
+func clampColour(c material.ColourTuple) material.ColourTuple {
+	return material.Colour(clamp(c.Red()), clamp(c.Green()), clamp(c.Blue()))
+}
+
+func clamp(v float64) float64 {
+	return math.Max(0, math.Min(1, v))
+}
+
+func maxOf3(a, b, c float64) float64 {
+	return math.Max(a, math.Max(b, c))
+}
+
+// heatStops are the colours of the diff image from no error to the largest.
+var heatStops = []material.ColourTuple{
+	material.Colour(0, 0, 0),
+	material.Colour(0, 0, 1),
+	material.Colour(0, 1, 0),
+	material.Colour(1, 1, 0),
+	material.Colour(1, 0, 0),
+}
+
+// heatColour maps t in [0, 1] onto heatStops.
+func heatColour(t float64) material.ColourTuple {
+	stops := heatStops
+	t = clamp(t)
+	pos := t * float64(len(stops)-1)
+	i := int(pos)
+	if i >= len(stops)-1 {
+		return stops[len(stops)-1]
+	}
+
+	frac := pos - float64(i)
+	return stops[i].Add(stops[i+1].Sub(stops[i]).Mul(frac))
+}
@@ -0,0 +1,124 @@
+package world
+
+import (
+	"math"
+	"path/filepath"
+	"testing"
+
+	"github.com/dannyroes/raytrace/data"
+	"github.com/dannyroes/raytrace/material"
+)
+
+func gradientCanvas(width, height int) CanvasType {
+	c := Canvas(width, height)
+	for x := 0; x < width; x++ {
+		for y := 0; y < height; y++ {
+			c.WritePixel(x, y, material.Colour(float64(x)/float64(width), float64(y)/float64(height), 0.5))
+		}
+	}
+
+	return c
+}
+
+func TestCompareIdentical(t *testing.T) {
+	a := gradientCanvas(16, 16)
+
+	res, err := Compare(a, a)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	if res.MaxError != 0 || res.RMSE != 0 || res.Differing != 0 {
+		t.Errorf("Expected no error, received %+v", res)
+	}
+
+	if !math.IsInf(res.PSNR, 1) {
+		t.Errorf("PSNR mismatch expected +Inf received %f", res.PSNR)
+	}
+
+	if !data.FloatEqual(res.SSIM, 1) {
+		t.Errorf("SSIM mismatch expected 1 received %f", res.SSIM)
+	}
+}
+
+func TestCompareDifferent(t *testing.T) {
+	a := gradientCanvas(16, 16)
+	b := gradientCanvas(16, 16)
+	b.WritePixel(0, 0, material.Colour(1, 1, 1))
+
+	res, err := Compare(a, b)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	if res.Differing != 1 {
+		t.Errorf("Differing mismatch expected %d received %d", 1, res.Differing)
+	}
+
+	if !data.FloatEqual(res.MaxError, 1) {
+		t.Errorf("MaxError mismatch expected %f received %f", 1.0, res.MaxError)
+	}
+
+	if res.SSIM >= 1 || res.PSNR <= 0 || math.IsInf(res.PSNR, 1) {
+		t.Errorf("Expected finite PSNR and SSIM below 1, received %+v", res)
+	}
+
+	if !material.ColourEqual(res.Diff.Pixel(0, 0), material.Colour(1, 0, 0)) {
+		t.Errorf("Expected largest error to be red, received %+v", res.Diff.Pixel(0, 0))
+	}
+
+	if !material.ColourEqual(res.Diff.Pixel(5, 5), material.Colour(0, 0, 0)) {
+		t.Errorf("Expected matching pixel to be black, received %+v", res.Diff.Pixel(5, 5))
+	}
+}
+
+func TestCompareSinglePixelWide(t *testing.T) {
+	a := gradientCanvas(1, 12)
+	b := gradientCanvas(1, 12)
+	b.WritePixel(0, 3, material.Colour(1, 1, 1))
+
+	res, err := Compare(a, b)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	if math.IsNaN(res.SSIM) || res.SSIM >= 1 {
+		t.Errorf("Expected SSIM below 1, received %f", res.SSIM)
+	}
+
+	res, err = Compare(a, a)
+	if err != nil || !data.FloatEqual(res.SSIM, 1) {
+		t.Errorf("Expected SSIM 1 comparing a 1x12 canvas with itself, received %f, %v", res.SSIM, err)
+	}
+}
+
+func TestCompareSizeMismatch(t *testing.T) {
+	_, err := Compare(Canvas(4, 4), Canvas(4, 5))
+	if err == nil {
+		t.Error("Expected error comparing canvases of different sizes")
+	}
+}
+
+func TestLoadPNG(t *testing.T) {
+	c := gradientCanvas(8, 6)
+	filename := filepath.Join(t.TempDir(), "gradient.png")
+
+	err := c.ToPNG(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := LoadPNG(filename)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	res, err := Compare(c.Quantize(), loaded)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	if res.MaxError != 0 {
+		t.Errorf("Expected saved png to match quantized canvas, max error %f", res.MaxError)
+	}
+}
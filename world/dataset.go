@@ -0,0 +1,268 @@
+package world
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"math/rand"
+	"os"
+
+	"github.com/dannyroes/raytrace/data"
+	"github.com/dannyroes/raytrace/shape"
+	"gopkg.in/yaml.v2"
+)
+
+// undistortIterations is how many fixed point steps Undistort takes, enough
+// to converge for the distortion found in ordinary lenses.
+const undistortIterations = 20
+
+// Distortion is the Brown-Conrady lens model with radial coefficients K1, K2
+// and K3 and tangential coefficients P1 and P2, in the order OpenCV uses. It
+// works on normalized image coordinates, with x to the right and y down.
+type Distortion struct {
+	K1 float64 `json:"k1"`
+	K2 float64 `json:"k2"`
+	P1 float64 `json:"p1"`
+	P2 float64 `json:"p2"`
+	K3 float64 `json:"k3"`
+}
+
+// Apply moves an undistorted point to where the lens images it.
+func (d Distortion) Apply(x, y float64) (float64, float64) {
+	r2 := x*x + y*y
+	radial := 1 + d.K1*r2 + d.K2*r2*r2 + d.K3*r2*r2*r2
+
+	xd := x*radial + 2*d.P1*x*y + d.P2*(r2+2*x*x)
+	yd := y*radial + d.P1*(r2+2*y*y) + 2*d.P2*x*y
+
+	return xd, yd
+}
+
+// Undistort finds the point that Apply moves to x, y.
+func (d Distortion) Undistort(x, y float64) (float64, float64) {
+	xu, yu := x, y
+	for i := 0; i < undistortIterations; i++ {
+		r2 := xu*xu + yu*yu
+		radial := 1 + d.K1*r2 + d.K2*r2*r2 + d.K3*r2*r2*r2
+		dx := 2*d.P1*xu*yu + d.P2*(r2+2*xu*xu)
+		dy := d.P1*(r2+2*yu*yu) + 2*d.P2*xu*yu
+
+		xu = (x - dx) / radial
+		yu = (y - dy) / radial
+	}
+
+	return xu, yu
+}
+
+// Intrinsics returns the 3x3 camera matrix for the image, with pixel centres
+// at whole numbers as OpenCV expects.
+func (c *CameraType) Intrinsics() data.Matrix {
+	f := 1 / c.PixelSize
+	return data.Matrix{
+		{f, 0, float64(c.HSize-1) / 2},
+		{0, f, float64(c.VSize-1) / 2},
+		{0, 0, 1},
+	}
+}
+
+// Extrinsics returns the world to camera transform with x to the right, y
+// down and z forward. The camera's own Transform looks down -z with x to the
+// left and y up; the world is left handed, so this has a negative
+// determinant.
+func (c *CameraType) Extrinsics() data.Matrix {
+	return data.Scaling(-1, -1, -1).Multiply(c.Transform)
+}
+
+// GroundTruth holds what the primary ray through each pixel hit. Depth is the
+// distance along the camera's z axis and is 0 where nothing was hit.
+// Instances holds 1 + the index in WorldType.Objects of the object hit, or 0.
+type GroundTruth struct {
+	Width     int
+	Height    int
+	Depth     [][]float64
+	Instances [][]int
+}
+
+// GroundTruth traces the primary ray for every pixel at the camera's size,
+// without supersampling.
+func (c *CameraType) GroundTruth(w WorldType) GroundTruth {
+	gt := GroundTruth{
+		Width:     c.HSize,
+		Height:    c.VSize,
+		Depth:     make([][]float64, c.HSize),
+		Instances: make([][]int, c.HSize),
+	}
+
+	instances := map[shape.Shape]int{}
+	for i, obj := range w.Objects {
+		collectShapes(obj, "", i, data.IdentityMatrix(), func(s lintShape) {
+			instances[s.shape] = s.top + 1
+		})
+	}
+
+	extrinsics := c.Extrinsics()
+
+	for x := 0; x < c.HSize; x++ {
+		gt.Depth[x] = make([]float64, c.VSize)
+		gt.Instances[x] = make([]int, c.VSize)
+
+		for y := 0; y < c.VSize; y++ {
+			r := c.RayForPixel(x, y)
+			h := w.Intersect(r).Hit()
+			if h.T == -1 {
+				continue
+			}
+
+			gt.Depth[x][y] = extrinsics.MultiplyTuple(r.Position(h.T)).Z
+			gt.Instances[x][y] = instances[h.Object]
+		}
+	}
+
+	return gt
+}
+
+// ToDepthPNG writes the depth as a 16 bit greyscale PNG, with each step
+// being scale units, so a scale of 0.001 stores millimetres for a scene in
+// metres. Depths too far to store are written as the largest value.
+func (g GroundTruth) ToDepthPNG(filename string, scale float64) error {
+	return writeGray16(filename, g.Width, g.Height, func(x, y int) uint16 {
+		return uint16(math.Min(math.Round(g.Depth[x][y]/scale), math.MaxUint16))
+	})
+}
+
+// ToInstancePNG writes the instance ids as a 16 bit greyscale PNG.
+func (g GroundTruth) ToInstancePNG(filename string) error {
+	return writeGray16(filename, g.Width, g.Height, func(x, y int) uint16 {
+		return uint16(g.Instances[x][y])
+	})
+}
+
+func writeGray16(filename string, width, height int, value func(x, y int) uint16) error {
+	im := image.NewGray16(image.Rect(0, 0, width, height))
+	for x := 0; x < width; x++ {
+		for y := 0; y < height; y++ {
+			im.SetGray16(x, y, color.Gray16{Y: value(x, y)})
+		}
+	}
+
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+
+	err = png.Encode(f, im)
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+// Pose places a camera at From looking towards To.
+type Pose struct {
+	From data.Tuple
+	To   data.Tuple
+	Up   data.Tuple
+}
+
+func (p Pose) Transform() data.Matrix {
+	return data.ViewTransform(p.From, p.To, p.Up)
+}
+
+type scenePose struct {
+	From []float64
+	To   []float64
+	Up   []float64
+}
+
+// LoadPoses reads a YAML list of camera poses, each with from, to and an
+// optional up in the same form as a scene camera.
+func LoadPoses(filename string) ([]Pose, error) {
+	f, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var items []scenePose
+	err = yaml.Unmarshal(f, &items)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", filename, err)
+	}
+
+	poses := make([]Pose, len(items))
+	for i, item := range items {
+		if item.Up == nil {
+			item.Up = []float64{0, 1, 0}
+		}
+
+		if len(item.From) != 3 || len(item.To) != 3 || len(item.Up) != 3 {
+			return nil, fmt.Errorf("%s: pose %d needs from, to and up with 3 values each", filename, i+1)
+		}
+
+		poses[i] = Pose{
+			From: data.Point(item.From[0], item.From[1], item.From[2]),
+			To:   data.Point(item.To[0], item.To[1], item.To[2]),
+			Up:   data.Vector(item.Up[0], item.Up[1], item.Up[2]),
+		}
+	}
+
+	return poses, nil
+}
+
+// CameraPose recovers the pose of the camera. To is the first surface along
+// the centre of view, or a point 5 units ahead if there is none.
+func CameraPose(c *CameraType, w WorldType) Pose {
+	inv := c.Transform.Invert()
+	from := inv.MultiplyTuple(data.Point(0, 0, 0))
+	forward := inv.MultiplyTuple(data.Vector(0, 0, -1)).Normalize()
+	up := inv.MultiplyTuple(data.Vector(0, 1, 0)).Normalize()
+
+	distance := 5.0
+	h := w.Intersect(data.Ray(from, forward)).Hit()
+	if h.T > 0 {
+		distance = h.T
+	}
+
+	return Pose{from, from.Add(forward.Mul(distance)), up}
+}
+
+// RandomPoses orbits the camera's pose around the point it looks at, turning
+// up to spread radians either side and moving up to 20% nearer or further
+// away. The same seed always gives the same poses.
+func RandomPoses(c *CameraType, w WorldType, n int, seed int64, spread float64) ([]Pose, error) {
+	if n < 0 {
+		return nil, fmt.Errorf("invalid number of poses %d", n)
+	}
+	if c.Transform == nil || !c.Transform.Invertible() {
+		return nil, fmt.Errorf("camera transform is not invertible")
+	}
+
+	rng := rand.New(rand.NewSource(seed))
+	base := CameraPose(c, w)
+
+	offset := base.From.Sub(base.To)
+	radius := offset.Magnitude()
+	azimuth := math.Atan2(offset.X, offset.Z)
+	elevation := math.Asin(offset.Y / radius)
+
+	uniform := func(limit float64) float64 {
+		return (rng.Float64()*2 - 1) * limit
+	}
+
+	poses := make([]Pose, n)
+	for i := range poses {
+		a := azimuth + uniform(spread)
+		e := math.Max(-1.4, math.Min(1.4, elevation+uniform(spread/2)))
+		r := radius * (1 + uniform(0.2))
+
+		to := base.To.Add(data.Vector(uniform(0.1*radius), uniform(0.1*radius), uniform(0.1*radius)))
+		from := to.Add(data.Vector(math.Cos(e)*math.Sin(a), math.Sin(e), math.Cos(e)*math.Cos(a)).Mul(r))
+
+		poses[i] = Pose{from, to, data.Vector(0, 1, 0)}
+	}
+
+	return poses, nil
+}
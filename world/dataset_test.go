@@ -0,0 +1,145 @@
+package world
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dannyroes/raytrace/data"
+)
+
+// project maps a world point to pixel coordinates using the camera's
+// intrinsics, extrinsics and distortion.
+func project(c *CameraType, p data.Tuple) (float64, float64) {
+	k := c.Intrinsics()
+	cam := c.Extrinsics().MultiplyTuple(p)
+
+	x, y := cam.X/cam.Z, cam.Y/cam.Z
+	if c.Distortion != nil {
+		x, y = c.Distortion.Apply(x, y)
+	}
+
+	return k[0][0]*x + k[0][2], k[1][1]*y + k[1][2]
+}
+
+func TestDistortionRoundTrip(t *testing.T) {
+	d := Distortion{K1: -0.2, K2: 0.05, P1: 0.001, P2: -0.002, K3: 0.01}
+
+	for _, p := range [][2]float64{{0, 0}, {0.3, -0.2}, {-0.5, 0.4}} {
+		xd, yd := d.Apply(p[0], p[1])
+		x, y := d.Undistort(xd, yd)
+
+		if math.Abs(x-p[0]) > 1e-6 || math.Abs(y-p[1]) > 1e-6 {
+			t.Errorf("Undistort mismatch expected %v received %f, %f", p, x, y)
+		}
+	}
+}
+
+func TestGroundTruthProjection(t *testing.T) {
+	for _, d := range []*Distortion{nil, {K1: -0.15, K2: 0.02, P1: 0.002, P2: 0.001}} {
+		c, w := traceTestScene()
+		c.Transform = data.ViewTransform(data.Point(1, 1.5, -5), data.Point(0, 0, 0), data.Vector(0, 1, 0))
+		c.Distortion = d
+
+		gt := c.GroundTruth(w)
+		for _, px := range [][2]int{{2, 8}, {5, 5}, {8, 9}} {
+			r := c.RayForPixel(px[0], px[1])
+			h := w.Intersect(r).Hit()
+			if h.T == -1 {
+				t.Fatalf("Expected pixel %v to hit", px)
+			}
+			p := r.Position(h.T)
+
+			u, v := project(c, p)
+			if math.Abs(u-float64(px[0])) > 1e-6 || math.Abs(v-float64(px[1])) > 1e-6 {
+				t.Errorf("Projection mismatch expected %v received %f, %f", px, u, v)
+			}
+
+			depth := gt.Depth[px[0]][px[1]]
+			if !data.FloatEqual(depth, c.Extrinsics().MultiplyTuple(p).Z) || depth <= 0 {
+				t.Errorf("Depth mismatch at %v received %f", px, depth)
+			}
+		}
+	}
+}
+
+func TestGroundTruthInstances(t *testing.T) {
+	c, w := traceTestScene()
+
+	gt := c.GroundTruth(w)
+
+	if gt.Instances[5][5] != 4 {
+		t.Errorf("Instance mismatch at centre expected %d received %d", 4, gt.Instances[5][5])
+	}
+
+	if gt.Instances[5][10] != 3 {
+		t.Errorf("Instance mismatch at floor expected %d received %d", 3, gt.Instances[5][10])
+	}
+
+	if gt.Instances[0][0] != 0 || gt.Depth[0][0] != 0 {
+		t.Errorf("Expected background at corner, received %d at %f", gt.Instances[0][0], gt.Depth[0][0])
+	}
+}
+
+func TestRandomPosesSeeded(t *testing.T) {
+	c, w := traceTestScene()
+
+	a, err := RandomPoses(c, w, 5, 42, math.Pi/4)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	b, _ := RandomPoses(c, w, 5, 42, math.Pi/4)
+	other, _ := RandomPoses(c, w, 5, 7, math.Pi/4)
+
+	for i := range a {
+		if !data.TupleEqual(a[i].From, b[i].From) || !data.TupleEqual(a[i].To, b[i].To) {
+			t.Errorf("Pose %d differs with the same seed: %+v and %+v", i, a[i], b[i])
+		}
+	}
+
+	if data.TupleEqual(a[0].From, other[0].From) {
+		t.Error("Expected a different seed to give different poses")
+	}
+
+	_, err = RandomPoses(c, w, -1, 42, math.Pi/4)
+	if err == nil {
+		t.Error("Expected an error for a negative number of poses")
+	}
+
+	base := CameraPose(c, w)
+	if !data.TupleEqual(base.From, data.Point(0, 0, -5)) || !data.TupleEqual(base.To, data.Point(0, 0, -2.5)) {
+		t.Errorf("Camera pose mismatch received %+v", base)
+	}
+}
+
+func TestLoadPoses(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "poses.yml")
+	err := os.WriteFile(filename, []byte(`- from: [0, 1, -5]
+  to: [0, 0, 0]
+- from: [5, 1, 0]
+  to: [0, 0, 0]
+  up: [0, 0, 1]
+`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	poses, err := LoadPoses(filename)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	if len(poses) != 2 {
+		t.Fatalf("Pose count mismatch expected %d received %d", 2, len(poses))
+	}
+
+	if !data.TupleEqual(poses[0].Up, data.Vector(0, 1, 0)) || !data.TupleEqual(poses[1].Up, data.Vector(0, 0, 1)) {
+		t.Errorf("Up mismatch received %+v and %+v", poses[0].Up, poses[1].Up)
+	}
+
+	if !data.TupleEqual(poses[1].From, data.Point(5, 1, 0)) {
+		t.Errorf("From mismatch expected %+v received %+v", data.Point(5, 1, 0), poses[1].From)
+	}
+}
@@ -0,0 +1,178 @@
+package world
+
+import (
+	"fmt"
+
+	"github.com/dannyroes/raytrace/data"
+	"github.com/dannyroes/raytrace/material"
+	"github.com/dannyroes/raytrace/shape"
+)
+
+// RayTrace records everything that happened to one ray: what it intersected,
+// how the hit was shaded and the reflection and refraction rays it spawned.
+type RayTrace struct {
+	Kind          string              `json:"kind"`
+	Origin        [3]float64          `json:"origin"`
+	Direction     [3]float64          `json:"direction"`
+	Remaining     int                 `json:"remaining"`
+	Intersections []TraceIntersection `json:"intersections"`
+	Hit           *TraceHit           `json:"hit,omitempty"`
+	Colour        [3]float64          `json:"colour"`
+}
+
+type TraceIntersection struct {
+	T      float64 `json:"t"`
+	Object string  `json:"object"`
+	U      float64 `json:"u,omitempty"`
+	V      float64 `json:"v,omitempty"`
+}
+
+type TraceHit struct {
+	T                       float64      `json:"t"`
+	Object                  string       `json:"object"`
+	Point                   [3]float64   `json:"point"`
+	EyeV                    [3]float64   `json:"eyev"`
+	NormalV                 [3]float64   `json:"normalv"`
+	ReflectV                [3]float64   `json:"reflectv"`
+	Inside                  bool         `json:"inside"`
+	OverPoint               [3]float64   `json:"over_point"`
+	UnderPoint              [3]float64   `json:"under_point"`
+	N1                      float64      `json:"n1"`
+	N2                      float64      `json:"n2"`
+	Lights                  []TraceLight `json:"lights"`
+	Surface                 [3]float64   `json:"surface"`
+	Reflectance             *float64     `json:"reflectance,omitempty"`
+	Reflection              *RayTrace    `json:"reflection,omitempty"`
+	ReflectionColour        [3]float64   `json:"reflection_colour"`
+	Refraction              *RayTrace    `json:"refraction,omitempty"`
+	RefractionColour        [3]float64   `json:"refraction_colour"`
+	TotalInternalReflection bool         `json:"total_internal_reflection,omitempty"`
+}
+
+type TraceLight struct {
+	Light    int        `json:"light"`
+	Position [3]float64 `json:"position"`
+	Shadowed bool       `json:"shadowed"`
+	Colour   [3]float64 `json:"colour"`
+}
+
+// TracePixel follows the primary ray for pixel x, y through the world the
+// same way ColourAt does, recording the whole ray tree. The pixel is at the
+// camera's own size, without supersampling.
+func (c *CameraType) TracePixel(w WorldType, x, y int) (*RayTrace, error) {
+	if x < 0 || y < 0 || x >= c.HSize || y >= c.VSize {
+		return nil, fmt.Errorf("pixel %d,%d is outside the %dx%d image", x, y, c.HSize, c.VSize)
+	}
+
+	t := tracer{w: w, names: map[shape.Shape]string{}}
+	for i, obj := range w.Objects {
+		collectShapes(obj, fmt.Sprintf("object %d (%s)", i+1, shape.Kind(obj)), i, data.IdentityMatrix(), func(s lintShape) {
+			t.names[s.shape] = s.path
+		})
+	}
+
+	return t.trace("primary", c.RayForPixel(x, y), c.MaxDepth), nil
+}
+
+type tracer struct {
+	w     WorldType
+	names map[shape.Shape]string
+}
+
+func (t tracer) name(s shape.Shape) string {
+	if n, ok := t.names[s]; ok {
+		return n
+	}
+
+	return shape.Kind(s)
+}
+
+func (t tracer) trace(kind string, r data.RayType, remain int) *RayTrace {
+	xs := t.w.Intersect(r)
+
+	rt := &RayTrace{
+		Kind:          kind,
+		Origin:        traceTuple(r.Origin),
+		Direction:     traceTuple(r.Direction),
+		Remaining:     remain,
+		Intersections: []TraceIntersection{},
+	}
+
+	for _, i := range xs {
+		rt.Intersections = append(rt.Intersections, TraceIntersection{i.T, t.name(i.Object), i.U, i.V})
+	}
+
+	h := xs.Hit()
+	if h.T == -1 {
+		return rt
+	}
+
+	comps := h.PrepareComputations(r, xs...)
+	hit := &TraceHit{
+		T:          comps.T,
+		Object:     t.name(comps.Object),
+		Point:      traceTuple(comps.Point),
+		EyeV:       traceTuple(comps.EyeV),
+		NormalV:    traceTuple(comps.NormalV),
+		ReflectV:   traceTuple(comps.ReflectV),
+		Inside:     comps.Inside,
+		OverPoint:  traceTuple(comps.OverPoint),
+		UnderPoint: traceTuple(comps.UnderPoint),
+		N1:         comps.N1,
+		N2:         comps.N2,
+		Lights:     []TraceLight{},
+	}
+	rt.Hit = hit
+
+	m := comps.Object.GetMaterial()
+	surface := material.Colour(0, 0, 0)
+	for i, l := range t.w.Lights {
+		shadowed := t.w.IsShadowed(comps.OverPoint, i)
+		colour := Lighting(m, comps.Object, l, comps.OverPoint, comps.EyeV, comps.NormalV, shadowed)
+		surface = surface.Add(colour)
+
+		hit.Lights = append(hit.Lights, TraceLight{i, traceTuple(l.Position), shadowed, traceColour(colour)})
+	}
+	hit.Surface = traceColour(surface)
+
+	reflect := material.Black
+	if m.Reflective != 0 && remain != 0 {
+		hit.Reflection = t.trace("reflection", data.Ray(comps.OverPoint, comps.ReflectV), remain-1)
+		reflect = colourFromTrace(hit.Reflection.Colour).Mul(m.Reflective)
+	}
+	hit.ReflectionColour = traceColour(reflect)
+
+	refract := material.Black
+	if m.Transparency != 0 && remain != 0 {
+		ray, ok := refractedRay(comps)
+		if ok {
+			hit.Refraction = t.trace("refraction", ray, remain-1)
+			refract = colourFromTrace(hit.Refraction.Colour).Mul(m.Transparency)
+		} else {
+			hit.TotalInternalReflection = true
+		}
+	}
+	hit.RefractionColour = traceColour(refract)
+
+	colour := surface.Add(reflect).Add(refract)
+	if m.Reflective > 0 && m.Transparency > 0 {
+		reflectance := comps.Schlick()
+		hit.Reflectance = &reflectance
+		colour = surface.Add(reflect.Mul(reflectance)).Add(refract.Mul(1 - reflectance))
+	}
+	rt.Colour = traceColour(colour)
+
+	return rt
+}
+
+func traceTuple(t data.Tuple) [3]float64 {
+	return [3]float64{t.X, t.Y, t.Z}
+}
+
+func traceColour(c material.ColourTuple) [3]float64 {
+	return [3]float64{c.Red(), c.Green(), c.Blue()}
+}
+
+func colourFromTrace(c [3]float64) material.ColourTuple {
+	return material.Colour(c[0], c[1], c[2])
+}
@@ -0,0 +1,101 @@
+package world
+
+import (
+	"math"
+	"testing"
+
+	"github.com/dannyroes/raytrace/data"
+	"github.com/dannyroes/raytrace/material"
+	"github.com/dannyroes/raytrace/shape"
+)
+
+func traceTestScene() (*CameraType, WorldType) {
+	w := DefaultWorld()
+
+	floor := shape.Plane()
+	floor.SetTransform(data.Translation(0, -1, 0))
+	m := material.Material()
+	m.Reflective = 0.5
+	floor.SetMaterial(m)
+
+	glass := shape.GlassSphere()
+	glass.SetTransform(data.Scaling(0.5, 0.5, 0.5).Translate(0, 0, -2))
+	m = glass.GetMaterial()
+	m.Reflective = 0.9
+	glass.SetMaterial(m)
+
+	w.Objects = append(w.Objects, floor, glass)
+
+	c := Camera(11, 11, math.Pi/2)
+	c.Transform = data.ViewTransform(data.Point(0, 0, -5), data.Point(0, 0, 0), data.Vector(0, 1, 0))
+
+	return c, w
+}
+
+func TestTracePixelMatchesColourAt(t *testing.T) {
+	c, w := traceTestScene()
+
+	for y := 0; y < c.VSize; y++ {
+		for x := 0; x < c.HSize; x++ {
+			trace, err := c.TracePixel(w, x, y)
+			if err != nil {
+				t.Fatalf("Unexpected error %v", err)
+			}
+
+			expected := w.ColourAt(c.RayForPixel(x, y), c.MaxDepth)
+			if !material.ColourEqual(colourFromTrace(trace.Colour), expected) {
+				t.Errorf("Colour mismatch at %d,%d expected %+v received %+v", x, y, expected, trace.Colour)
+			}
+		}
+	}
+}
+
+func TestTracePixelTree(t *testing.T) {
+	c, w := traceTestScene()
+
+	trace, err := c.TracePixel(w, 5, 5)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	if trace.Kind != "primary" || trace.Hit == nil {
+		t.Fatalf("Expected primary ray to hit, received %+v", trace)
+	}
+
+	if trace.Hit.Object != "object 4 (sphere)" {
+		t.Errorf("Hit object mismatch expected %q received %q", "object 4 (sphere)", trace.Hit.Object)
+	}
+
+	if trace.Hit.N1 != 1 || trace.Hit.N2 != 1.5 {
+		t.Errorf("Refractive index mismatch expected 1, 1.5 received %f, %f", trace.Hit.N1, trace.Hit.N2)
+	}
+
+	if len(trace.Hit.Lights) != 1 {
+		t.Errorf("Light count mismatch expected %d received %d", 1, len(trace.Hit.Lights))
+	}
+
+	if trace.Hit.Reflectance == nil {
+		t.Error("Expected reflectance for a reflective, transparent material")
+	}
+
+	if trace.Hit.Reflection == nil || trace.Hit.Reflection.Kind != "reflection" {
+		t.Errorf("Expected reflection ray, received %+v", trace.Hit.Reflection)
+	}
+
+	if trace.Hit.Refraction == nil || trace.Hit.Refraction.Kind != "refraction" {
+		t.Fatalf("Expected refraction ray, received %+v", trace.Hit.Refraction)
+	}
+
+	if trace.Hit.Refraction.Remaining != c.MaxDepth-1 {
+		t.Errorf("Remaining mismatch expected %d received %d", c.MaxDepth-1, trace.Hit.Refraction.Remaining)
+	}
+}
+
+func TestTracePixelOutside(t *testing.T) {
+	c, w := traceTestScene()
+
+	_, err := c.TracePixel(w, c.HSize, 0)
+	if err == nil {
+		t.Error("Expected error tracing a pixel outside the image")
+	}
+}
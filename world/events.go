@@ -0,0 +1,143 @@
+package world
+
+import (
+	"fmt"
+	"io"
+	"sync/atomic"
+	"time"
+
+	movingaverage "github.com/RobinUS2/golang-moving-average"
+	"github.com/dannyroes/raytrace/material"
+)
+
+// DefaultProgressInterval is how often RenderProgress is sent when
+// CameraType.ProgressInterval is not set.
+const DefaultProgressInterval = time.Second
+
+// RenderObserver receives events as a render runs. The methods are called one
+// at a time from the goroutine that called RenderContext, so an observer that
+// blocks holds up the render.
+type RenderObserver interface {
+	RenderStarted(e RenderStarted)
+	TileFinished(e TileFinished)
+	RenderProgress(e RenderProgress)
+	RenderFinished(e RenderFinished)
+}
+
+// RenderStarted describes the image being rendered. Supersample is the
+// number of samples each pixel takes along each axis.
+type RenderStarted struct {
+	Width       int
+	Height      int
+	Supersample int
+	Tiles       int
+	Workers     int
+}
+
+// TileFinished carries the colours of a tile, row by row, as soon as it is
+// rendered.
+type TileFinished struct {
+	Tile   Tile
+	Pixels []material.ColourTuple
+}
+
+type RenderProgress struct {
+	Pixels      int
+	TotalPixels int
+	Percent     float64
+	Elapsed     time.Duration
+	Remaining   time.Duration
+}
+
+// RenderFinished is sent once the render stops, whether it completed or ctx
+// was cancelled, in which case Err is set.
+type RenderFinished struct {
+	Pixels   int
+	Duration time.Duration
+	Stats    RayStats
+	Err      error
+}
+
+// progress counts the samples taken by a render, sending RenderProgress to
+// the camera's observer every ProgressInterval and RenderFinished at the end.
+type progress struct {
+	c          *CameraType
+	total      int
+	done       int
+	start      time.Time
+	lastUpdate time.Time
+	lastDone   int
+	ma         *movingaverage.MovingAverage
+}
+
+func newProgress(c *CameraType, total int) *progress {
+	now := time.Now()
+	return &progress{c: c, total: total, start: now, lastUpdate: now, ma: movingaverage.New(30)}
+}
+
+func (p *progress) add(samples int) {
+	p.done += samples
+
+	if p.c.Observer == nil || time.Since(p.lastUpdate) <= p.c.progressInterval() {
+		return
+	}
+
+	p.ma.Add(float64(p.done-p.lastDone) / time.Since(p.lastUpdate).Seconds())
+	p.lastUpdate = time.Now()
+	p.lastDone = p.done
+
+	p.c.Observer.RenderProgress(RenderProgress{
+		Pixels:      p.done,
+		TotalPixels: p.total,
+		Percent:     float64(p.done) / float64(p.total) * 100,
+		Elapsed:     time.Since(p.start),
+		Remaining:   time.Duration(float64(p.total-p.done)/p.ma.Avg()) * time.Second,
+	})
+}
+
+func (p *progress) finish(w *WorldType, err error) {
+	p.c.notify(func(o RenderObserver) {
+		finished := RenderFinished{Pixels: p.done, Duration: time.Since(p.start), Err: err}
+		if w.Stats != nil {
+			finished.Stats = RayStats{
+				Primary:   atomic.LoadUint64(&w.Stats.Primary),
+				Secondary: atomic.LoadUint64(&w.Stats.Secondary),
+				Shadow:    atomic.LoadUint64(&w.Stats.Shadow),
+			}
+		}
+		o.RenderFinished(finished)
+	})
+}
+
+// ProgressPrinter writes a line when a render starts, a progress line that
+// overwrites itself as it goes and a summary when it finishes.
+type ProgressPrinter struct {
+	Out io.Writer
+}
+
+func NewProgressPrinter(out io.Writer) *ProgressPrinter {
+	return &ProgressPrinter{Out: out}
+}
+
+func (p *ProgressPrinter) RenderStarted(e RenderStarted) {
+	fmt.Fprintf(p.Out, "Rendering width %d; height %d\n", e.Width, e.Height)
+}
+
+func (p *ProgressPrinter) TileFinished(e TileFinished) {}
+
+func (p *ProgressPrinter) RenderProgress(e RenderProgress) {
+	fmt.Fprintf(p.Out, "%s\r", progressStatus(e))
+}
+
+func (p *ProgressPrinter) RenderFinished(e RenderFinished) {
+	if e.Err != nil {
+		fmt.Fprintf(p.Out, "Stopped after %d pixels in %-40v\n", e.Pixels, e.Duration)
+		return
+	}
+
+	fmt.Fprintf(p.Out, "Rendered %d pixels in %-40v\n", e.Pixels, e.Duration)
+}
+
+func progressStatus(e RenderProgress) string {
+	return fmt.Sprintf("Elapsed: %v - %.2f%% complete, estimate remaining: %-10v", e.Elapsed.Truncate(time.Second), e.Percent, e.Remaining.Truncate(time.Second))
+}
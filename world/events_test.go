@@ -0,0 +1,122 @@
+package world
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+type recordingObserver struct {
+	started  []RenderStarted
+	pixels   int
+	progress []RenderProgress
+	finished []RenderFinished
+	cancel   context.CancelFunc
+}
+
+func (r *recordingObserver) RenderStarted(e RenderStarted) {
+	r.started = append(r.started, e)
+}
+
+func (r *recordingObserver) TileFinished(e TileFinished) {
+	r.pixels += len(e.Pixels)
+	if r.cancel != nil {
+		r.cancel()
+	}
+}
+
+func (r *recordingObserver) RenderProgress(e RenderProgress) {
+	r.progress = append(r.progress, e)
+}
+
+func (r *recordingObserver) RenderFinished(e RenderFinished) {
+	r.finished = append(r.finished, e)
+}
+
+func TestRenderEvents(t *testing.T) {
+	c, w := traceTestScene()
+	c.HSize, c.VSize = 20, 10
+	c.Supersample = 2
+	c.TileSize = 4
+	c.CalcPixelSize()
+	c.ProgressInterval = time.Nanosecond
+	w.Stats = &RayStats{}
+
+	obs := &recordingObserver{}
+	c.Observer = obs
+	c.Render(w)
+
+	if len(obs.started) != 1 || obs.started[0].Width != 20 || obs.started[0].Height != 10 || obs.started[0].Tiles != 15 {
+		t.Errorf("Expected one start event for a 20x10 render in 15 tiles, received %+v", obs.started)
+	}
+
+	if obs.pixels != 200 {
+		t.Errorf("Tile pixel mismatch expected %d received %d", 200, obs.pixels)
+	}
+
+	if len(obs.progress) == 0 || obs.progress[len(obs.progress)-1].TotalPixels != 200 {
+		t.Errorf("Expected progress events, received %+v", obs.progress)
+	}
+
+	if len(obs.finished) != 1 {
+		t.Fatalf("Expected one finish event, received %d", len(obs.finished))
+	}
+
+	// Each pixel takes four samples.
+	f := obs.finished[0]
+	if f.Pixels != 200 || f.Err != nil || f.Stats.Primary != 800 {
+		t.Errorf("Finish event mismatch received %+v", f)
+	}
+
+	if c.HSize != 20 || c.VSize != 10 {
+		t.Errorf("Expected camera size unchanged by supersampling, received %dx%d", c.HSize, c.VSize)
+	}
+}
+
+func TestRenderEventsCancelled(t *testing.T) {
+	c, w := traceTestScene()
+	c.HSize, c.VSize = 40, 40
+	c.TileSize = 4
+	c.Workers = 1
+	c.CalcPixelSize()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	obs := &recordingObserver{cancel: cancel}
+	c.Observer = obs
+
+	_, err := c.RenderContext(ctx, w)
+	if err != context.Canceled {
+		t.Errorf("Expected context.Canceled, received %v", err)
+	}
+
+	if len(obs.finished) != 1 || obs.finished[0].Err != context.Canceled {
+		t.Errorf("Expected finish event with the cancellation, received %+v", obs.finished)
+	}
+
+	if obs.finished[0].Pixels >= 1600 {
+		t.Errorf("Expected render to stop early, received %d pixels", obs.finished[0].Pixels)
+	}
+}
+
+func TestProgressPrinter(t *testing.T) {
+	out := &bytes.Buffer{}
+	p := NewProgressPrinter(out)
+
+	p.RenderStarted(RenderStarted{Width: 20, Height: 10})
+	p.RenderProgress(RenderProgress{Pixels: 50, TotalPixels: 200, Percent: 25, Elapsed: 2 * time.Second, Remaining: 6 * time.Second})
+	p.RenderFinished(RenderFinished{Pixels: 200, Duration: 8 * time.Second})
+
+	expected := []string{
+		"Rendering width 20; height 10\n",
+		"Elapsed: 2s - 25.00% complete, estimate remaining: 6s        \r",
+		"Rendered 200 pixels in 8s",
+	}
+
+	for _, e := range expected {
+		if !strings.Contains(out.String(), e) {
+			t.Errorf("Expected output to contain %q, received %q", e, out.String())
+		}
+	}
+}
@@ -0,0 +1,262 @@
+package world
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"github.com/dannyroes/raytrace/material"
+)
+
+type FilterKind int
+
+const (
+	FilterBox FilterKind = iota
+	FilterTent
+	FilterGaussian
+	FilterMitchell
+	FilterLanczos
+)
+
+var filterKinds = []FilterKind{FilterBox, FilterTent, FilterGaussian, FilterMitchell, FilterLanczos}
+
+func (k FilterKind) String() string {
+	switch k {
+	case FilterBox:
+		return "box"
+	case FilterTent:
+		return "tent"
+	case FilterGaussian:
+		return "gaussian"
+	case FilterMitchell:
+		return "mitchell"
+	case FilterLanczos:
+		return "lanczos"
+	}
+
+	return "unknown"
+}
+
+func ParseFilterKind(name string) (FilterKind, error) {
+	for _, k := range filterKinds {
+		if k.String() == name {
+			return k, nil
+		}
+	}
+
+	return FilterBox, fmt.Errorf("unknown filter %q, expected box, tent, gaussian, mitchell or lanczos", name)
+}
+
+// defaultRadius is the radius, in pixels, each kind of filter is usually
+// used with.
+func (k FilterKind) defaultRadius() float64 {
+	switch k {
+	case FilterTent:
+		return 1
+	case FilterGaussian:
+		return 1.5
+	case FilterMitchell:
+		return 2
+	case FilterLanczos:
+		return 3
+	}
+
+	return 0.5
+}
+
+// Filter reconstructs pixels from samples. Each sample adds to every pixel
+// whose centre is within Radius of it, weighted by the filter's value at the
+// distance between them, and each pixel is divided by the sum of its
+// weights. A box filter with a radius of half a pixel is a plain average of
+// the samples inside the pixel. Radius 0 uses the kind's usual radius.
+type Filter struct {
+	Kind   FilterKind
+	Radius float64
+}
+
+func (f Filter) radius() float64 {
+	if f.Radius > 0 {
+		return f.Radius
+	}
+
+	return f.Kind.defaultRadius()
+}
+
+func (f Filter) String() string {
+	return fmt.Sprintf("%s radius %g", f.Kind, f.radius())
+}
+
+// Weight returns the filter's value for a sample dx, dy pixels from the
+// centre of a pixel. Every filter is separable.
+func (f Filter) Weight(dx, dy float64) float64 {
+	return f.weight1D(dx) * f.weight1D(dy)
+}
+
+func (f Filter) weight1D(d float64) float64 {
+	r := f.radius()
+	d = math.Abs(d)
+	if d > r {
+		return 0
+	}
+
+	switch f.Kind {
+	case FilterTent:
+		return 1 - d/r
+	case FilterGaussian:
+		// Shifted down so it reaches 0 at the radius.
+		const alpha = 2
+		return math.Max(0, math.Exp(-alpha*d*d)-math.Exp(-alpha*r*r))
+	case FilterMitchell:
+		return mitchell(2 * d / r)
+	case FilterLanczos:
+		return sinc(d) * sinc(d/r)
+	}
+
+	return 1
+}
+
+// mitchell is the Mitchell-Netravali cubic with B = C = 1/3, for x in [0, 2].
+func mitchell(x float64) float64 {
+	const b, c = 1.0 / 3, 1.0 / 3
+
+	if x < 1 {
+		return ((12-9*b-6*c)*x*x*x + (-18+12*b+6*c)*x*x + (6 - 2*b)) / 6
+	}
+
+	return ((-b-6*c)*x*x*x + (6*b+30*c)*x*x + (-12*b-48*c)*x + (8*b + 24*c)) / 6
+}
+
+func sinc(x float64) float64 {
+	if x < 1e-5 {
+		return 1
+	}
+
+	return math.Sin(math.Pi*x) / (math.Pi * x)
+}
+
+// filmSample is a colour seen through a point on the image, in pixels.
+type filmSample struct {
+	x      float64
+	y      float64
+	colour material.ColourTuple
+}
+
+func averageSamples(samples []filmSample) material.ColourTuple {
+	colour := material.Colour(0, 0, 0)
+	for _, s := range samples {
+		colour = colour.Add(s.colour)
+	}
+
+	return colour.Div(float64(len(samples)))
+}
+
+// filmScale is the fixed point scale of the sums kept by a film.
+const filmScale = 1 << 32
+
+// film accumulates filtered samples into pixels. The sums are kept in fixed
+// point so that adding the same samples in any order gives the same image,
+// keeping renders independent of which worker finishes first.
+type film struct {
+	width  int
+	height int
+	filter Filter
+	// sums holds red, green, blue and weight for each pixel, row by row.
+	sums [][4]int64
+}
+
+func newFilm(width, height int, filter Filter) *film {
+	return &film{width: width, height: height, filter: filter, sums: make([][4]int64, width*height)}
+}
+
+func (f *film) add(samples []filmSample) {
+	for _, s := range samples {
+		f.splat(s)
+	}
+}
+
+// splat adds the sample to every pixel within the filter's radius.
+func (f *film) splat(s filmSample) {
+	r := f.filter.radius()
+
+	x0 := int(math.Max(0, math.Ceil(s.x-0.5-r)))
+	x1 := int(math.Min(float64(f.width-1), math.Floor(s.x-0.5+r)))
+	y0 := int(math.Max(0, math.Ceil(s.y-0.5-r)))
+	y1 := int(math.Min(float64(f.height-1), math.Floor(s.y-0.5+r)))
+
+	for y := y0; y <= y1; y++ {
+		for x := x0; x <= x1; x++ {
+			weight := f.filter.Weight(float64(x)+0.5-s.x, float64(y)+0.5-s.y)
+			if weight == 0 {
+				continue
+			}
+
+			sum := &f.sums[y*f.width+x]
+			sum[0] += int64(math.Round(s.colour.Red() * weight * filmScale))
+			sum[1] += int64(math.Round(s.colour.Green() * weight * filmScale))
+			sum[2] += int64(math.Round(s.colour.Blue() * weight * filmScale))
+			sum[3] += int64(math.Round(weight * filmScale))
+		}
+	}
+}
+
+// image divides each pixel's sum by its weight. Pixels no sample reached
+// with a positive weight are left black.
+func (f *film) image() CanvasType {
+	image := Canvas(f.width, f.height)
+
+	for y := 0; y < f.height; y++ {
+		for x := 0; x < f.width; x++ {
+			sum := f.sums[y*f.width+x]
+			if sum[3] <= 0 {
+				continue
+			}
+
+			weight := float64(sum[3])
+			image.WritePixel(x, y, material.Colour(float64(sum[0])/weight, float64(sum[1])/weight, float64(sum[2])/weight))
+		}
+	}
+
+	return image
+}
+
+// renderFiltered renders the image at its final size, taking Supersample x
+// Supersample samples per pixel, placed by Sampler if the camera has one,
+// and reconstructing the pixels from them with Filter.
+//
+// A Region is widened by the filter's reach so the pixels on its edges see
+// the same samples they would in a full render, then cropped back.
+func (c *CameraType) renderFiltered(ctx context.Context, w WorldType) (CanvasType, error) {
+	if c.Region != nil {
+		margin := int(math.Ceil(c.Filter.radius() + 0.5))
+		wide := *c
+		region := c.Region.grow(margin, c.HSize, c.VSize)
+		wide.Region = &region
+
+		image, err := wide.renderFilm(ctx, w)
+		cropped := Canvas(c.Region.Width, c.Region.Height)
+		cropped.Draw(image, region.X-c.Region.X, region.Y-c.Region.Y)
+		return cropped, err
+	}
+
+	return c.renderFilm(ctx, w)
+}
+
+// renderFilm renders the camera's image through its Filter as it is, see
+// renderFiltered.
+func (c *CameraType) renderFilm(ctx context.Context, w WorldType) (CanvasType, error) {
+	width, height := c.ImageSize()
+	f := newFilm(width, height, *c.Filter)
+	offsets := gridOffsets(c.Supersample)
+	n := c.samplesPerPixel()
+
+	err := c.renderImage(ctx, &w, discardTiles{}, width, height, pass{samples: func(w *WorldType, x, y int) []filmSample {
+		samples := make([]filmSample, n)
+		for i := range samples {
+			px, py := c.samplePosition(x, y, i, offsets)
+			samples[i] = filmSample{px, py, c.samplePixel(w, px, py)}
+		}
+		return samples
+	}}, f)
+
+	return f.image(), err
+}
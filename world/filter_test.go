@@ -0,0 +1,118 @@
+package world
+
+import (
+	"math"
+	"testing"
+
+	"github.com/dannyroes/raytrace/material"
+	"github.com/dannyroes/raytrace/sampler"
+)
+
+func TestFilterWeights(t *testing.T) {
+	tests := []struct {
+		filter   Filter
+		d        float64
+		expected float64
+	}{
+		{Filter{Kind: FilterBox}, 0.4, 1},
+		{Filter{Kind: FilterBox}, 0.6, 0},
+		{Filter{Kind: FilterTent}, 0, 1},
+		{Filter{Kind: FilterTent}, 0.5, 0.5},
+		{Filter{Kind: FilterTent, Radius: 2}, 1, 0.5},
+		{Filter{Kind: FilterGaussian}, 1.5, 0},
+		{Filter{Kind: FilterGaussian}, 0, 1 - math.Exp(-4.5)},
+		{Filter{Kind: FilterMitchell}, 0, 8.0 / 9},
+		{Filter{Kind: FilterMitchell}, 2, 0},
+		{Filter{Kind: FilterLanczos}, 0, 1},
+		{Filter{Kind: FilterLanczos}, 1, 0},
+		{Filter{Kind: FilterLanczos}, 3.5, 0},
+	}
+
+	for _, test := range tests {
+		w := test.filter.weight1D(test.d)
+		if math.Abs(w-test.expected) > 1e-9 {
+			t.Errorf("%s at %g: expected %f received %f", test.filter, test.d, test.expected, w)
+		}
+	}
+
+	f := Filter{Kind: FilterTent}
+	if w := f.Weight(0.5, 0.5); math.Abs(w-0.25) > 1e-9 {
+		t.Errorf("Expected a separable weight of 0.25, received %f", w)
+	}
+}
+
+func TestParseFilterKind(t *testing.T) {
+	for _, k := range filterKinds {
+		parsed, err := ParseFilterKind(k.String())
+		if err != nil || parsed != k {
+			t.Errorf("Parse mismatch expected %s received %s, %v", k, parsed, err)
+		}
+	}
+
+	_, err := ParseFilterKind("sharpen")
+	if err == nil {
+		t.Error("Expected error for an unknown filter")
+	}
+}
+
+func TestFilmSplat(t *testing.T) {
+	f := newFilm(3, 3, Filter{Kind: FilterTent})
+	f.add([]filmSample{{1.5, 1.5, material.Colour(1, 0.5, 0)}})
+	image := f.image()
+
+	// A sample at the centre of a pixel reaches no other pixel centre
+	// with a tent of radius 1.
+	if image.Pixel(1, 1) != material.Colour(1, 0.5, 0) || image.Pixel(0, 1) != material.Colour(0, 0, 0) {
+		t.Errorf("Expected only the centre pixel set, received %+v and %+v", image.Pixel(1, 1), image.Pixel(0, 1))
+	}
+
+	f.add([]filmSample{{1.25, 1.5, material.Colour(0, 0, 1)}})
+	image = f.image()
+
+	// The second sample is 0.25 from pixel 1, 1 and 0.75 from pixel 0, 1.
+	expected := material.Colour(1, 0.5, 0.75).Div(1.75)
+	if !material.ColourEqual(image.Pixel(1, 1), expected) || !material.ColourEqual(image.Pixel(0, 1), material.Colour(0, 0, 1)) {
+		t.Errorf("Splat mismatch received %+v and %+v", image.Pixel(1, 1), image.Pixel(0, 1))
+	}
+}
+
+func TestRenderBoxFilterMatchesSupersample(t *testing.T) {
+	c, w := traceTestScene()
+	c.Supersample = 3
+	expected := c.Render(w)
+
+	c.Filter = &Filter{Kind: FilterBox}
+	image := c.Render(w)
+
+	for x := 0; x < c.HSize; x++ {
+		for y := 0; y < c.VSize; y++ {
+			if !material.ColourEqual(image.Pixel(x, y), expected.Pixel(x, y)) {
+				t.Fatalf("Pixel %d,%d mismatch expected %+v received %+v", x, y, expected.Pixel(x, y), image.Pixel(x, y))
+			}
+		}
+	}
+}
+
+func TestRenderFilteredIndependentOfWorkers(t *testing.T) {
+	for _, k := range filterKinds {
+		c, w := traceTestScene()
+		c.Supersample = 2
+		c.Sampler = sampler.Halton(1)
+		c.Filter = &Filter{Kind: k}
+		c.Workers = 1
+		expected := c.Render(w)
+
+		c.Workers = 4
+		c.TileSize = 3
+		c.TileOrder = TileSpiral
+		image := c.Render(w)
+
+		for x := 0; x < c.HSize; x++ {
+			for y := 0; y < c.VSize; y++ {
+				if image.Pixel(x, y) != expected.Pixel(x, y) {
+					t.Fatalf("%s: pixel %d,%d differs between worker counts: %+v and %+v", k, x, y, expected.Pixel(x, y), image.Pixel(x, y))
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package world
+
+import (
+	"reflect"
+
+	"github.com/dannyroes/raytrace/shape"
+)
+
+// matrixBytes is the heap used by a 4x4 data.Matrix beyond its slice header:
+// four row headers plus sixteen float64 values.
+const matrixBytes = 4*24 + 16*8
+
+type SceneStats struct {
+	Objects    int
+	Primitives int
+	Triangles  int
+	Groups     int
+	Csgs       int
+	Lights     int
+	Memory     uint64
+	Bounds     shape.Bounds
+}
+
+// Inspect walks every object in the world, counting what was built and
+// estimating the memory it occupies. Bounds is in world space.
+func Inspect(w WorldType) SceneStats {
+	stats := SceneStats{
+		Objects: len(w.Objects),
+		Lights:  len(w.Lights),
+		Bounds:  shape.EmptyBounds(),
+	}
+
+	for _, obj := range w.Objects {
+		stats.Bounds = stats.Bounds.Union(shape.TransformBounds(obj.Bounds(), obj.GetTransform()))
+
+		shape.Walk(obj, func(s shape.Shape, depth int) {
+			stats.Memory += ShapeMemory(s)
+
+			switch s.(type) {
+			case *shape.GroupType:
+				stats.Groups++
+			case *shape.CsgType:
+				stats.Csgs++
+			case *shape.TriangleType:
+				stats.Triangles++
+				stats.Primitives++
+			default:
+				stats.Primitives++
+			}
+		})
+	}
+
+	stats.Memory += uint64(cap(w.Objects)) * uint64(reflect.TypeOf((*shape.Shape)(nil)).Elem().Size())
+	stats.Memory += uint64(cap(w.Lights)) * uint64(reflect.TypeOf(Light{}).Size())
+
+	return stats
+}
+
+// ShapeMemory estimates the bytes held by a single shape, not counting any
+// shapes nested inside it.
+func ShapeMemory(s shape.Shape) uint64 {
+	size := uint64(reflect.TypeOf(s).Elem().Size()) + matrixBytes
+
+	if p := s.GetMaterial().Pattern; p != nil {
+		size += uint64(reflect.TypeOf(p).Elem().Size()) + matrixBytes
+	}
+
+	if g, ok := s.(*shape.GroupType); ok {
+		size += uint64(cap(g.Children)) * uint64(reflect.TypeOf((*shape.Shape)(nil)).Elem().Size())
+		if g.GroupBounds != nil {
+			size += uint64(reflect.TypeOf(shape.Bounds{}).Size())
+		}
+	}
+
+	return size
+}
@@ -0,0 +1,53 @@
+package world
+
+import (
+	"testing"
+
+	"github.com/dannyroes/raytrace/data"
+	"github.com/dannyroes/raytrace/material"
+	"github.com/dannyroes/raytrace/shape"
+)
+
+func TestInspect(t *testing.T) {
+	w := World()
+	w.Lights = append(w.Lights, PointLight(data.Point(0, 10, 0), material.Colour(1, 1, 1)))
+
+	s := shape.Sphere()
+	s.SetTransform(data.Translation(-5, 0, 0))
+
+	g := shape.Group()
+	g.AddChild(shape.Triangle(data.Point(0, 0, 0), data.Point(1, 0, 0), data.Point(0, 1, 0)))
+	g.AddChild(shape.Csg(shape.CsgUnion, shape.Sphere(), shape.Cube()))
+	g.SetTransform(data.Translation(0, 0, 3))
+
+	w.Objects = []shape.Shape{s, g}
+
+	stats := Inspect(w)
+
+	if stats.Objects != 2 {
+		t.Errorf("Objects mismatch expected %d received %d", 2, stats.Objects)
+	}
+	if stats.Primitives != 4 {
+		t.Errorf("Primitives mismatch expected %d received %d", 4, stats.Primitives)
+	}
+	if stats.Triangles != 1 {
+		t.Errorf("Triangles mismatch expected %d received %d", 1, stats.Triangles)
+	}
+	if stats.Groups != 1 {
+		t.Errorf("Groups mismatch expected %d received %d", 1, stats.Groups)
+	}
+	if stats.Csgs != 1 {
+		t.Errorf("Csgs mismatch expected %d received %d", 1, stats.Csgs)
+	}
+	if stats.Lights != 1 {
+		t.Errorf("Lights mismatch expected %d received %d", 1, stats.Lights)
+	}
+	if stats.Memory == 0 {
+		t.Error("Expected a memory estimate")
+	}
+
+	expected := shape.Bounds{Min: data.Point(-6, -1, -1), Max: data.Point(1, 1, 4)}
+	if !data.TupleEqual(stats.Bounds.Min, expected.Min) || !data.TupleEqual(stats.Bounds.Max, expected.Max) {
+		t.Errorf("Bounds mismatch expected %+v received %+v", expected, stats.Bounds)
+	}
+}
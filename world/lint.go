@@ -0,0 +1,326 @@
+package world
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/dannyroes/raytrace/data"
+	"github.com/dannyroes/raytrace/shape"
+)
+
+type Severity int
+
+const (
+	SeverityWarning Severity = iota
+	SeverityError
+)
+
+func (s Severity) String() string {
+	if s == SeverityError {
+		return "error"
+	}
+	return "warning"
+}
+
+type Diagnostic struct {
+	Severity Severity
+	Code     string
+	Object   string
+	Message  string
+}
+
+func (d Diagnostic) String() string {
+	if d.Object == "" {
+		return fmt.Sprintf("%s: %s [%s]", d.Severity, d.Message, d.Code)
+	}
+	return fmt.Sprintf("%s: %s: %s [%s]", d.Severity, d.Object, d.Message, d.Code)
+}
+
+// lintShape is a shape along with its position in the object tree and
+// its object to world transform.
+type lintShape struct {
+	shape     shape.Shape
+	path      string
+	top       int
+	transform data.Matrix
+}
+
+// LintScene loads a scene file and lints it, also reporting definitions
+// that are never referenced.
+func LintScene(filename string) ([]Diagnostic, error) {
+	c, w, err := LoadScene(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	items, err := readScene(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	diags := Lint(c, w)
+	for _, name := range unusedDefinitions(items) {
+		diags = append(diags, Diagnostic{
+			Severity: SeverityWarning,
+			Code:     "unused-define",
+			Message:  fmt.Sprintf("definition %q is never used", name),
+		})
+	}
+
+	return diags, nil
+}
+
+// Lint checks a loaded scene for common mistakes that would otherwise only
+// show up after a long render.
+func Lint(c *CameraType, w WorldType) []Diagnostic {
+	var diags []Diagnostic
+
+	add := func(sev Severity, code, object, msg string, args ...interface{}) {
+		diags = append(diags, Diagnostic{sev, code, object, fmt.Sprintf(msg, args...)})
+	}
+
+	if c == nil || c.Transform == nil {
+		add(SeverityError, "no-camera", "", "scene has no camera")
+	} else {
+		if c.HSize <= 0 || c.VSize <= 0 {
+			add(SeverityError, "camera-size", "camera", "image size is %dx%d", c.HSize, c.VSize)
+		}
+		if c.FieldOfView <= 0 || c.FieldOfView >= math.Pi {
+			add(SeverityError, "camera-fov", "camera", "field of view %g is outside (0, pi)", c.FieldOfView)
+		}
+		if !c.Transform.Invertible() {
+			add(SeverityError, "non-invertible", "camera", "view transform is not invertible")
+		}
+	}
+
+	if len(w.Lights) == 0 {
+		add(SeverityWarning, "no-lights", "", "scene has no lights")
+	}
+
+	var prims []lintShape
+	valid := make([]bool, len(w.Objects))
+
+	for i, obj := range w.Objects {
+		valid[i] = true
+		collectShapes(obj, fmt.Sprintf("object %d (%s)", i+1, shape.Kind(obj)), i, data.IdentityMatrix(), func(s lintShape) {
+			if !s.shape.GetTransform().Invertible() {
+				add(SeverityError, "non-invertible", s.path, "transform is not invertible")
+				valid[s.top] = false
+				return
+			}
+			if p := s.shape.GetMaterial().Pattern; p != nil && !p.GetTransform().Invertible() {
+				add(SeverityError, "non-invertible", s.path, "pattern transform is not invertible")
+				valid[s.top] = false
+			}
+
+			switch s.shape.(type) {
+			case *shape.GroupType, *shape.CsgType:
+				return
+			}
+			prims = append(prims, s)
+		})
+	}
+
+	for _, p := range prims {
+		m := p.shape.GetMaterial()
+		if m.Transparency > 0 && data.FloatEqual(m.RefractiveIndex, 1) {
+			add(SeverityWarning, "refractive-index", p.path, "transparent material has refractive index 1 so it will not bend light")
+		}
+		if m.Reflective > 0 && m.Transparency > 0 {
+			add(SeverityWarning, "reflective-transparent", p.path, "reflective transparent material ignores its reflective value, reflection and refraction are blended by Schlick reflectance instead")
+		}
+	}
+
+	if c != nil && c.Transform != nil && c.Transform.Invertible() {
+		for i, obj := range w.Objects {
+			if !valid[i] {
+				continue
+			}
+			b := shape.TransformBounds(shape.TransformBounds(obj.Bounds(), obj.GetTransform()), c.Transform)
+			if !b.IsEmpty() && b.Min.Z > 0 {
+				add(SeverityWarning, "behind-camera", fmt.Sprintf("object %d (%s)", i+1, shape.Kind(obj)), "object is entirely behind the camera")
+			}
+		}
+	}
+
+	for li, l := range w.Lights {
+		for i, obj := range w.Objects {
+			if !valid[i] {
+				continue
+			}
+
+			// A closed object around both the light and the camera is a room
+			// rather than a mistake.
+			var enclosing []shape.Shape
+			if c != nil && c.Transform != nil && c.Transform.Invertible() {
+				enclosing = shapesContaining(obj, c.Transform.Invert().MultiplyTuple(data.Point(0, 0, 0)))
+			}
+
+			for _, s := range shapesContaining(obj, l.Position) {
+				if !containsShape(enclosing, s) {
+					add(SeverityWarning, "light-inside", pathFor(prims, s), "light %d is inside an opaque object", li+1)
+				}
+			}
+		}
+	}
+
+	for i := 0; i < len(prims); i++ {
+		for j := i + 1; j < len(prims); j++ {
+			if !valid[prims[i].top] || !valid[prims[j].top] {
+				continue
+			}
+			if coplanar(prims[i], prims[j]) {
+				add(SeverityWarning, "coplanar-planes", prims[j].path, "plane overlaps %s", prims[i].path)
+			}
+		}
+	}
+
+	return diags
+}
+
+func collectShapes(s shape.Shape, path string, top int, parent data.Matrix, fn func(lintShape)) {
+	transform := parent
+	if s.GetTransform() != nil {
+		transform = parent.Multiply(s.GetTransform())
+	}
+	fn(lintShape{s, path, top, transform})
+
+	if !s.GetTransform().Invertible() {
+		return
+	}
+
+	switch v := s.(type) {
+	case *shape.GroupType:
+		for i, c := range v.Children {
+			collectShapes(c, fmt.Sprintf("%s > child %d (%s)", path, i+1, shape.Kind(c)), top, transform, fn)
+		}
+	case *shape.CsgType:
+		collectShapes(v.Left(), path+" > left ("+shape.Kind(v.Left())+")", top, transform, fn)
+		collectShapes(v.Right(), path+" > right ("+shape.Kind(v.Right())+")", top, transform, fn)
+	}
+}
+
+func pathFor(prims []lintShape, s shape.Shape) string {
+	for _, p := range prims {
+		if p.shape == s {
+			return p.path
+		}
+	}
+	return shape.Kind(s)
+}
+
+// shapesContaining casts a ray from p and returns the closed, opaque shapes
+// it leaves an odd number of times, which must contain p.
+func shapesContaining(obj shape.Shape, p data.Tuple) []shape.Shape {
+	r := data.Ray(p, data.Vector(0.5773, 0.5774, 0.5775).Normalize())
+
+	counts := map[shape.Shape]int{}
+	var order []shape.Shape
+	for _, x := range shape.Intersects(obj, r) {
+		if x.T <= data.Epsilon {
+			continue
+		}
+		if counts[x.Object] == 0 {
+			order = append(order, x.Object)
+		}
+		counts[x.Object]++
+	}
+
+	var inside []shape.Shape
+	for _, s := range order {
+		if counts[s]%2 == 1 && isSolid(s) && s.GetMaterial().Transparency == 0 && s.CastsShadow() {
+			inside = append(inside, s)
+		}
+	}
+
+	return inside
+}
+
+func containsShape(list []shape.Shape, s shape.Shape) bool {
+	for _, x := range list {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
+
+func isSolid(s shape.Shape) bool {
+	switch v := s.(type) {
+	case *shape.SphereType, *shape.CubeType:
+		return true
+	case *shape.CylinderType:
+		return v.Closed
+	case *shape.ConeType:
+		return v.Closed
+	}
+	return false
+}
+
+func coplanar(a, b lintShape) bool {
+	_, okA := a.shape.(*shape.PlaneType)
+	_, okB := b.shape.(*shape.PlaneType)
+	if !okA || !okB {
+		return false
+	}
+
+	normal := func(p lintShape) data.Tuple {
+		n := p.transform.Invert().Transpose().MultiplyTuple(data.Vector(0, 1, 0))
+		n.W = 0
+		return n.Normalize()
+	}
+
+	na := normal(a)
+	nb := normal(b)
+	if !data.FloatEqual(math.Abs(data.Dot(na, nb)), 1) {
+		return false
+	}
+
+	pa := a.transform.MultiplyTuple(data.Point(0, 0, 0))
+	pb := b.transform.MultiplyTuple(data.Point(0, 0, 0))
+	return data.FloatEqual(data.Dot(na, pb.Sub(pa)), 0)
+}
+
+// unusedDefinitions returns the names of defines that no later item refers
+// to, in the order they were declared.
+func unusedDefinitions(items []map[string]interface{}) []string {
+	var names []string
+	used := map[string]bool{}
+
+	var mark func(v interface{})
+	mark = func(v interface{}) {
+		switch val := v.(type) {
+		case string:
+			used[val] = true
+		case []interface{}:
+			for _, x := range val {
+				mark(x)
+			}
+		case map[interface{}]interface{}:
+			for _, x := range val {
+				mark(x)
+			}
+		}
+	}
+
+	for _, item := range items {
+		for key, val := range item {
+			if key == "define" {
+				if name, ok := val.(string); ok {
+					names = append(names, name)
+				}
+				continue
+			}
+			mark(val)
+		}
+	}
+
+	var unused []string
+	for _, name := range names {
+		if !used[name] {
+			unused = append(unused, name)
+		}
+	}
+
+	return unused
+}
@@ -0,0 +1,203 @@
+package world
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dannyroes/raytrace/data"
+	"github.com/dannyroes/raytrace/material"
+	"github.com/dannyroes/raytrace/shape"
+)
+
+func lintCodes(diags []Diagnostic) map[string]int {
+	codes := map[string]int{}
+	for _, d := range diags {
+		codes[d.Code]++
+	}
+	return codes
+}
+
+func lintCamera() *CameraType {
+	c := Camera(100, 50, math.Pi/3)
+	c.Transform = data.ViewTransform(data.Point(0, 1, -5), data.Point(0, 1, 0), data.Vector(0, 1, 0))
+	return c
+}
+
+func TestLintClean(t *testing.T) {
+	w := DefaultWorld()
+	diags := Lint(lintCamera(), w)
+
+	if len(diags) != 0 {
+		t.Errorf("Expected no diagnostics, received %+v", diags)
+	}
+}
+
+func TestLintCamera(t *testing.T) {
+	w := DefaultWorld()
+
+	codes := lintCodes(Lint(&CameraType{}, w))
+	if codes["no-camera"] != 1 {
+		t.Errorf("Expected no-camera diagnostic, received %+v", codes)
+	}
+
+	c := lintCamera()
+	c.HSize = 0
+	codes = lintCodes(Lint(c, w))
+	if codes["camera-size"] != 1 {
+		t.Errorf("Expected camera-size diagnostic, received %+v", codes)
+	}
+}
+
+func TestLintNonInvertible(t *testing.T) {
+	w := DefaultWorld()
+	s := shape.Sphere()
+	s.SetTransform(data.Scaling(1, 0, 1))
+
+	g := shape.Group()
+	g.AddChild(s)
+	w.Objects = append(w.Objects, g)
+
+	diags := Lint(lintCamera(), w)
+	codes := lintCodes(diags)
+	if codes["non-invertible"] != 1 {
+		t.Fatalf("Expected non-invertible diagnostic, received %+v", diags)
+	}
+
+	for _, d := range diags {
+		if d.Code == "non-invertible" && d.Object != "object 3 (group) > child 1 (sphere)" {
+			t.Errorf("Object path mismatch received %s", d.Object)
+		}
+		if d.Code == "non-invertible" && d.Severity != SeverityError {
+			t.Errorf("Expected error severity, received %s", d.Severity)
+		}
+	}
+}
+
+func TestLintLightInside(t *testing.T) {
+	w := DefaultWorld()
+	w.Lights = []Light{PointLight(data.Point(0, 0, 0), material.Colour(1, 1, 1))}
+
+	codes := lintCodes(Lint(lintCamera(), w))
+	if codes["light-inside"] != 2 {
+		t.Errorf("Expected light inside both spheres, received %+v", codes)
+	}
+
+	glass := shape.GlassSphere()
+	w.Objects = []shape.Shape{glass}
+	codes = lintCodes(Lint(lintCamera(), w))
+	if codes["light-inside"] != 0 {
+		t.Errorf("Expected light inside glass to be allowed, received %+v", codes)
+	}
+
+	room := shape.Cube()
+	room.SetTransform(data.Scaling(10, 10, 10))
+	w.Objects = []shape.Shape{room}
+	codes = lintCodes(Lint(lintCamera(), w))
+	if codes["light-inside"] != 0 {
+		t.Errorf("Expected light inside a room with the camera to be allowed, received %+v", codes)
+	}
+}
+
+func TestLintBehindCamera(t *testing.T) {
+	w := DefaultWorld()
+	s := shape.Sphere()
+	s.SetTransform(data.Translation(0, 1, -10))
+	w.Objects = append(w.Objects, s)
+
+	codes := lintCodes(Lint(lintCamera(), w))
+	if codes["behind-camera"] != 1 {
+		t.Errorf("Expected behind-camera diagnostic, received %+v", codes)
+	}
+}
+
+func TestLintMaterials(t *testing.T) {
+	w := DefaultWorld()
+	m := material.Material()
+	m.Transparency = 0.9
+	m.Reflective = 0.9
+	w.Objects[0].SetMaterial(m)
+
+	codes := lintCodes(Lint(lintCamera(), w))
+	if codes["refractive-index"] != 1 || codes["reflective-transparent"] != 1 {
+		t.Errorf("Expected material diagnostics, received %+v", codes)
+	}
+}
+
+func TestLintReflectiveGlass(t *testing.T) {
+	w := DefaultWorld()
+	m := material.Material()
+	m.Transparency = 0.9
+	m.Reflective = 0.9
+	m.RefractiveIndex = 1.5
+	w.Objects[0].SetMaterial(m)
+
+	codes := lintCodes(Lint(lintCamera(), w))
+	if codes["refractive-index"] != 0 || codes["reflective-transparent"] != 1 {
+		t.Errorf("Expected only reflective-transparent diagnostic, received %+v", codes)
+	}
+}
+
+func TestLintCoplanarPlanes(t *testing.T) {
+	w := DefaultWorld()
+	p1 := shape.Plane()
+	p2 := shape.Plane()
+	p2.SetTransform(data.Translation(3, 0, 2).RotateY(1))
+	p3 := shape.Plane()
+	p3.SetTransform(data.Translation(0, 1, 0))
+	w.Objects = append(w.Objects, p1, p2, p3)
+
+	codes := lintCodes(Lint(lintCamera(), w))
+	if codes["coplanar-planes"] != 1 {
+		t.Errorf("Expected one coplanar-planes diagnostic, received %+v", codes)
+	}
+}
+
+func TestLintSceneUnusedDefinitions(t *testing.T) {
+	scene := filepath.Join(t.TempDir(), "scene.yml")
+	err := os.WriteFile(scene, []byte(`- add: camera
+  width: 20
+  height: 10
+  field-of-view: 0.785
+  from: [0, 1.5, -5]
+  to: [0, 1, 0]
+  up: [0, 1, 0]
+
+- add: light
+  at: [-10, 10, -10]
+  intensity: [1, 1, 1]
+
+- define: base-material
+  value:
+    diffuse: 0.7
+
+- define: red-material
+  extend: base-material
+  value:
+    colour: [1, 0, 0]
+
+- define: spare-material
+  value:
+    colour: [0, 1, 0]
+
+- add: sphere
+  material: red-material
+`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	diags, err := LintScene(scene)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	if len(diags) != 1 || diags[0].Code != "unused-define" {
+		t.Fatalf("Expected one unused-define diagnostic, received %+v", diags)
+	}
+
+	if diags[0].Message != `definition "spare-material" is never used` {
+		t.Errorf("Message mismatch received %s", diags[0].Message)
+	}
+}
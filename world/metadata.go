@@ -0,0 +1,254 @@
+package world
+
+import (
+	"bytes"
+	"compress/zlib"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"image/png"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// pngSignature starts every PNG file, and pngHeaderEnd is where the IHDR
+// chunk that must come first ends.
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+const pngHeaderEnd = 8 + 4 + 4 + 13 + 4
+
+// ToPNGWithMetadata writes the canvas like ToPNG, adding each entry of meta
+// as a text chunk. Keys and values that fit in Latin-1 are stored as tEXt,
+// anything else as uncompressed UTF-8 iTXt.
+func (c CanvasType) ToPNGWithMetadata(filename string, meta map[string]string) error {
+	var buf bytes.Buffer
+	err := png.Encode(&buf, c.ToImage())
+	if err != nil {
+		return err
+	}
+
+	encoded := buf.Bytes()
+	var out bytes.Buffer
+	out.Write(encoded[:pngHeaderEnd])
+
+	err = writeTextChunks(&out, meta)
+	if err != nil {
+		return err
+	}
+
+	out.Write(encoded[pngHeaderEnd:])
+
+	return os.WriteFile(filename, out.Bytes(), 0644)
+}
+
+// writeTextChunks writes a text chunk for each entry of meta, sorted by key.
+func writeTextChunks(w io.Writer, meta map[string]string) error {
+	keys := make([]string, 0, len(meta))
+	for k := range meta {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		if len(k) == 0 || len(k) > 79 || !isLatin1(k) {
+			return fmt.Errorf("invalid png text key %q", k)
+		}
+
+		var err error
+		if isLatin1(meta[k]) {
+			err = writeChunk(w, "tEXt", append(append(latin1(k), 0), latin1(meta[k])...))
+		} else {
+			// keyword, null, compression flag, compression method, empty
+			// language tag and translated keyword, then the text.
+			chunk := append(latin1(k), 0, 0, 0, 0, 0)
+			err = writeChunk(w, "iTXt", append(chunk, meta[k]...))
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ReadPNGMetadata returns the tEXt, zTXt and iTXt entries of a PNG file.
+func ReadPNGMetadata(filename string) (map[string]string, error) {
+	f, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	if !bytes.HasPrefix(f, pngSignature) {
+		return nil, fmt.Errorf("%s: not a png file", filename)
+	}
+
+	meta := map[string]string{}
+	r := bytes.NewReader(f[len(pngSignature):])
+
+	for {
+		var length uint32
+		err = binary.Read(r, binary.BigEndian, &length)
+		if err == io.EOF {
+			return meta, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", filename, err)
+		}
+
+		// The type and CRC follow the data, and PNG caps chunks at 2^31-1
+		// bytes, so a longer length is a corrupt file rather than one to
+		// allocate for.
+		if length > math.MaxInt32 || int64(length)+8 > int64(r.Len()) {
+			return nil, fmt.Errorf("%s: truncated chunk", filename)
+		}
+
+		header := make([]byte, 4+int(length)+4)
+		_, err = io.ReadFull(r, header)
+		if err != nil {
+			return nil, fmt.Errorf("%s: truncated chunk", filename)
+		}
+
+		kind, data := string(header[:4]), header[4:4+length]
+		if kind == "IEND" {
+			return meta, nil
+		}
+
+		key, value, err := parseTextChunk(kind, data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s chunk: %v", filename, kind, err)
+		}
+		if key != "" {
+			meta[key] = value
+		}
+	}
+}
+
+func parseTextChunk(kind string, data []byte) (string, string, error) {
+	switch kind {
+	case "tEXt":
+		key, text, ok := cutNull(data)
+		if !ok {
+			return "", "", errors.New("missing keyword separator")
+		}
+		return fromLatin1(key), fromLatin1(text), nil
+
+	case "zTXt":
+		key, rest, ok := cutNull(data)
+		if !ok || len(rest) < 1 {
+			return "", "", errors.New("missing keyword separator")
+		}
+		text, err := inflate(rest[1:])
+		return fromLatin1(key), fromLatin1(text), err
+
+	case "iTXt":
+		key, rest, ok := cutNull(data)
+		if !ok || len(rest) < 2 {
+			return "", "", errors.New("missing keyword separator")
+		}
+		compressed := rest[0] == 1
+		// Skip the language tag and translated keyword.
+		_, rest, ok = cutNull(rest[2:])
+		if ok {
+			_, rest, ok = cutNull(rest)
+		}
+		if !ok {
+			return "", "", errors.New("missing language separator")
+		}
+		if compressed {
+			text, err := inflate(rest)
+			return fromLatin1(key), string(text), err
+		}
+		return fromLatin1(key), string(rest), nil
+	}
+
+	return "", "", nil
+}
+
+// SceneHash returns the SHA-256 of a scene file together with every file it
+// loads, so that editing a model changes the hash as well.
+func SceneHash(filename string) (string, error) {
+	files, err := SceneFiles(filename)
+	if err != nil {
+		return "", err
+	}
+
+	h := sha256.New()
+	for _, f := range files {
+		contents, err := os.ReadFile(f)
+		if err != nil {
+			return "", err
+		}
+
+		rel, err := filepath.Rel(filepath.Dir(filename), f)
+		if err != nil {
+			rel = f
+		}
+		fmt.Fprintf(h, "%s\x00%d\x00", filepath.ToSlash(rel), len(contents))
+		h.Write(contents)
+	}
+
+	return fmt.Sprintf("%x", h.Sum(nil)), nil
+}
+
+func writeChunk(w io.Writer, kind string, data []byte) error {
+	crc := crc32.NewIEEE()
+	crc.Write([]byte(kind))
+	crc.Write(data)
+
+	chunk := make([]byte, len(data)+12)
+	binary.BigEndian.PutUint32(chunk, uint32(len(data)))
+	copy(chunk[4:], kind)
+	copy(chunk[8:], data)
+	binary.BigEndian.PutUint32(chunk[8+len(data):], crc.Sum32())
+
+	_, err := w.Write(chunk)
+	return err
+}
+
+func isLatin1(s string) bool {
+	for _, r := range s {
+		if r > 0xff || r == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func latin1(s string) []byte {
+	b := make([]byte, 0, len(s))
+	for _, r := range s {
+		b = append(b, byte(r))
+	}
+	return b
+}
+
+func fromLatin1(b []byte) string {
+	r := make([]rune, len(b))
+	for i, c := range b {
+		r[i] = rune(c)
+	}
+	return string(r)
+}
+
+func cutNull(b []byte) ([]byte, []byte, bool) {
+	i := bytes.IndexByte(b, 0)
+	if i < 0 {
+		return b, nil, false
+	}
+	return b[:i], b[i+1:], true
+}
+
+func inflate(b []byte) ([]byte, error) {
+	r, err := zlib.NewReader(bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	return io.ReadAll(r)
+}
@@ -0,0 +1,111 @@
+package world
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dannyroes/raytrace/material"
+)
+
+func TestPNGMetadataRoundTrip(t *testing.T) {
+	c := Canvas(4, 3)
+	c.Fill(material.Colour(0.2, 0.4, 0.6))
+	filename := filepath.Join(t.TempDir(), "meta.png")
+
+	meta := map[string]string{
+		"Scene":       "scenes/café.yml",
+		"Supersample": "2",
+		"Comment":     "日本語",
+	}
+
+	err := c.ToPNGWithMetadata(filename, meta)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	read, err := ReadPNGMetadata(filename)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	if len(read) != len(meta) {
+		t.Errorf("Entry count mismatch expected %d received %d", len(meta), len(read))
+	}
+
+	for k, v := range meta {
+		if read[k] != v {
+			t.Errorf("Value mismatch for %s expected %q received %q", k, v, read[k])
+		}
+	}
+
+	loaded, err := LoadPNG(filename)
+	if err != nil {
+		t.Fatalf("Expected png with metadata to decode, received %v", err)
+	}
+
+	res, _ := Compare(c.Quantize(), loaded)
+	if res.MaxError != 0 {
+		t.Errorf("Expected image to be unchanged by metadata, max error %f", res.MaxError)
+	}
+}
+
+func TestPNGMetadataInvalidKey(t *testing.T) {
+	err := Canvas(1, 1).ToPNGWithMetadata(filepath.Join(t.TempDir(), "bad.png"), map[string]string{"": "value"})
+	if err == nil {
+		t.Error("Expected error for an empty key")
+	}
+}
+
+func TestReadPNGMetadataNotPNG(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "scene.ppm")
+	err := Canvas(1, 1).ToPPMFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = ReadPNGMetadata(filename)
+	if err == nil {
+		t.Error("Expected error reading metadata from a ppm file")
+	}
+}
+
+func TestReadPNGMetadataCorruptLength(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "corrupt.png")
+	data := append([]byte{}, pngSignature...)
+	data = append(data, 0xff, 0xff, 0xff, 0xf0)
+	data = append(data, "tEXt"...)
+	err := os.WriteFile(filename, data, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = ReadPNGMetadata(filename)
+	if err == nil {
+		t.Error("Expected error for a chunk longer than the file")
+	}
+}
+
+func TestSceneHash(t *testing.T) {
+	scene := writeTestScene(t)
+
+	first, err := SceneHash(scene)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	again, _ := SceneHash(scene)
+	if first != again {
+		t.Errorf("Expected the same hash twice, received %s and %s", first, again)
+	}
+
+	err = os.WriteFile(filepath.Join(filepath.Dir(scene), "models", "square.obj"), []byte(testObj+"f 2 3 4\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	changed, _ := SceneHash(scene)
+	if changed == first {
+		t.Error("Expected editing a model to change the scene hash")
+	}
+}
@@ -0,0 +1,123 @@
+package world
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/dannyroes/raytrace/material"
+)
+
+// TerminalPreview draws a downscaled copy of an image in a terminal using
+// upper half block characters, with the foreground colour as the top pixel
+// and the background colour as the bottom one. Each Draw moves the cursor
+// back over the previous one so the preview updates in place.
+//
+// As a RenderObserver it collects tiles as they finish and redraws on each
+// progress event, in place of the ProgressPrinter's line.
+type TerminalPreview struct {
+	Out     io.Writer
+	Columns int
+	Rows    int
+	lines   int
+	image   CanvasType
+}
+
+func NewTerminalPreview(out io.Writer, columns, rows int) *TerminalPreview {
+	return &TerminalPreview{Out: out, Columns: columns, Rows: rows}
+}
+
+// Draw writes the image followed by a status line.
+func (p *TerminalPreview) Draw(image CanvasType, status string) {
+	columns, rows := p.size(image.Width, image.Height)
+
+	var b strings.Builder
+	if p.lines > 0 {
+		fmt.Fprintf(&b, "\x1b[%dA\r", p.lines)
+	}
+
+	for row := 0; row < rows; row++ {
+		for col := 0; col < columns; col++ {
+			top := sampleCell(image, col, row*2, columns, rows*2)
+			bottom := sampleCell(image, col, row*2+1, columns, rows*2)
+
+			tr, tg, tb := terminalColour(top)
+			br, bg, bb := terminalColour(bottom)
+			fmt.Fprintf(&b, "\x1b[38;2;%d;%d;%dm\x1b[48;2;%d;%d;%dm▀", tr, tg, tb, br, bg, bb)
+		}
+		b.WriteString("\x1b[0m\n")
+	}
+
+	fmt.Fprintf(&b, "\x1b[2K%s\n", status)
+	p.lines = rows + 1
+
+	io.WriteString(p.Out, b.String())
+}
+
+func (p *TerminalPreview) RenderStarted(e RenderStarted) {
+	p.image = Canvas(e.Width, e.Height)
+	p.lines = 0
+}
+
+func (p *TerminalPreview) TileFinished(e TileFinished) {
+	TileResult{Tile: e.Tile, Pixels: e.Pixels}.copyTo(p.image)
+}
+
+func (p *TerminalPreview) RenderProgress(e RenderProgress) {
+	p.Draw(p.image, progressStatus(e))
+}
+
+func (p *TerminalPreview) RenderFinished(e RenderFinished) {
+	status := fmt.Sprintf("Rendered %d pixels in %v", e.Pixels, e.Duration)
+	if e.Err != nil {
+		status = fmt.Sprintf("Stopped after %d pixels in %v", e.Pixels, e.Duration)
+	}
+
+	p.Draw(p.image, status)
+}
+
+// size fits the image into the preview's columns and rows, keeping its aspect
+// ratio. Each row holds two pixels of the preview.
+func (p *TerminalPreview) size(width, height int) (int, int) {
+	columns := p.Columns
+	if columns > width {
+		columns = width
+	}
+	if columns < 1 {
+		columns = 1
+	}
+
+	rows := (columns*height/width + 1) / 2
+	if p.Rows > 0 && rows > p.Rows {
+		rows = p.Rows
+		columns = rows * 2 * width / height
+		if columns < 1 {
+			columns = 1
+		}
+	}
+	if rows < 1 {
+		rows = 1
+	}
+
+	return columns, rows
+}
+
+// sampleCell returns the image pixel at the centre of cell x, y when the image
+// is divided into columns by rows cells.
+func sampleCell(image CanvasType, x, y, columns, rows int) material.ColourTuple {
+	px := (2*x + 1) * image.Width / (2 * columns)
+	py := (2*y + 1) * image.Height / (2 * rows)
+
+	if px >= image.Width {
+		px = image.Width - 1
+	}
+	if py >= image.Height {
+		py = image.Height - 1
+	}
+
+	return image.Pixel(px, py)
+}
+
+func terminalColour(c material.ColourTuple) (int, int, int) {
+	return material.GetCappedColour(c.Red(), 255), material.GetCappedColour(c.Green(), 255), material.GetCappedColour(c.Blue(), 255)
+}
@@ -0,0 +1,71 @@
+package world
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/dannyroes/raytrace/material"
+)
+
+func TestPreviewSize(t *testing.T) {
+	tests := []struct {
+		columns, rows int
+		width, height int
+		expectColumns int
+		expectRows    int
+	}{
+		{80, 40, 400, 300, 80, 30},
+		{80, 10, 400, 300, 26, 10},
+		{80, 40, 20, 10, 20, 5},
+		{80, 24, 10, 1000, 1, 24},
+	}
+
+	for _, tc := range tests {
+		p := NewTerminalPreview(&bytes.Buffer{}, tc.columns, tc.rows)
+		columns, rows := p.size(tc.width, tc.height)
+
+		if columns != tc.expectColumns || rows != tc.expectRows {
+			t.Errorf("Size mismatch for %dx%d expected %dx%d received %dx%d", tc.width, tc.height, tc.expectColumns, tc.expectRows, columns, rows)
+		}
+	}
+}
+
+func TestPreviewDraw(t *testing.T) {
+	image := Canvas(4, 4)
+	image.Fill(material.Colour(1, 0, 0))
+	for x := 0; x < 4; x++ {
+		image.WritePixel(x, 3, material.Colour(0, 0, 1))
+	}
+
+	out := &bytes.Buffer{}
+	p := NewTerminalPreview(out, 4, 10)
+	p.Draw(image, "status")
+
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("Line count mismatch expected %d received %d: %q", 3, len(lines), out.String())
+	}
+
+	if strings.Count(lines[0], "▀") != 4 {
+		t.Errorf("Expected 4 cells in the first row, received %q", lines[0])
+	}
+
+	if !strings.Contains(lines[0], "\x1b[38;2;255;0;0m\x1b[48;2;255;0;0m") {
+		t.Errorf("Expected red cells in the first row, received %q", lines[0])
+	}
+
+	if !strings.Contains(lines[1], "\x1b[38;2;255;0;0m\x1b[48;2;0;0;255m") {
+		t.Errorf("Expected red over blue cells in the second row, received %q", lines[1])
+	}
+
+	if !strings.HasSuffix(lines[2], "status") {
+		t.Errorf("Expected status line, received %q", lines[2])
+	}
+
+	out.Reset()
+	p.Draw(image, "status")
+	if !strings.HasPrefix(out.String(), "\x1b[3A\r") {
+		t.Errorf("Expected redraw to move the cursor up 3 lines, received %q", out.String()[:8])
+	}
+}
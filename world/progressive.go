@@ -0,0 +1,191 @@
+package world
+
+import (
+	"context"
+	"time"
+
+	"github.com/dannyroes/raytrace/material"
+)
+
+// progressiveStrides are the spacings of the coarse passes a progressive
+// render starts with, each filling in the pixels the one before skipped.
+var progressiveStrides = []int{8, 4, 2, 1}
+
+// RenderProgressive renders the world at the output size in passes, each
+// leaving a complete image. The first passes take one sample from every
+// 8th, then every 4th, 2nd and finally every pixel, with the gaps filled
+// from the nearest pixel already sampled. Each pass after that adds one more
+// of the Supersample x Supersample samples to every pixel, so the finished
+// image matches Render. Samples are placed by the camera's Sampler if it has
+// one.
+//
+// snapshot, if set, is called with the image so far whenever interval has
+// passed since the last call. If ctx is cancelled the image so far is
+// returned along with ctx.Err(), which makes it possible to stop once the
+// image looks good enough.
+func (c *CameraType) RenderProgressive(ctx context.Context, w WorldType, interval time.Duration, snapshot func(image CanvasType)) (CanvasType, error) {
+	err := c.checkRegion()
+	if err != nil {
+		return CanvasType{}, err
+	}
+
+	width, height := c.ImageSize()
+	r := newRefinement(width, height, c.Supersample)
+	offsets := r.offsets()
+
+	var passes []pass
+	for i, stride := range progressiveStrides {
+		stride, coarser := stride, 0
+		if i > 0 {
+			coarser = progressiveStrides[i-1]
+		}
+		passes = append(passes, pass{
+			include: func(x, y int) bool {
+				return isAnchor(x, y, stride) && !isAnchor(x, y, coarser)
+			},
+			sample: r.sampler(c, 0, offsets),
+		})
+	}
+	for i := range offsets[1:] {
+		passes = append(passes, pass{sample: r.sampler(c, i+1, offsets)})
+	}
+
+	tiles := Tiles(width, height, c.TileSize, c.TileOrder)
+
+	c.notify(func(o RenderObserver) {
+		o.RenderStarted(RenderStarted{width, height, c.Supersample, len(tiles) * len(passes), c.workers()})
+	})
+
+	p := newProgress(c, width*height*len(offsets))
+	lastSnapshot := time.Now()
+
+	for _, ps := range passes {
+		for result := range c.runPass(ctx, &w, tiles, ps) {
+			samples := r.add(result, ps.include)
+
+			if c.Observer != nil {
+				c.Observer.TileFinished(TileFinished{result.Tile, r.tile(result.Tile)})
+			}
+			p.add(samples)
+
+			if snapshot != nil && time.Since(lastSnapshot) >= interval {
+				snapshot(r.image())
+				lastSnapshot = time.Now()
+			}
+		}
+
+		if ctx.Err() != nil {
+			break
+		}
+	}
+
+	p.finish(&w, ctx.Err())
+
+	return r.image(), ctx.Err()
+}
+
+// isAnchor reports whether x, y is sampled by the coarse pass with the given
+// stride. Nothing is an anchor for a stride of 0.
+func isAnchor(x, y, stride int) bool {
+	return stride > 0 && x%stride == 0 && y%stride == 0
+}
+
+// refinement accumulates the samples of a progressive render.
+type refinement struct {
+	width  int
+	height int
+	n      int
+	sum    []material.ColourTuple
+	count  []int
+}
+
+func newRefinement(width, height, supersample int) *refinement {
+	if supersample < 1 {
+		supersample = 1
+	}
+
+	return &refinement{
+		width:  width,
+		height: height,
+		n:      supersample,
+		sum:    make([]material.ColourTuple, width*height),
+		count:  make([]int, width*height),
+	}
+}
+
+// offsets returns the positions within a pixel of the samples Render takes
+// when supersampling, the one nearest the centre first.
+func (r *refinement) offsets() [][2]float64 {
+	grid := gridOffsets(r.n)
+	first := r.n/2*r.n + r.n/2
+
+	offsets := [][2]float64{grid[first]}
+	offsets = append(offsets, grid[:first]...)
+	return append(offsets, grid[first+1:]...)
+}
+
+// sampler returns a function taking the i'th sample of a pixel.
+func (r *refinement) sampler(c *CameraType, i int, offsets [][2]float64) func(w *WorldType, x, y int) material.ColourTuple {
+	return func(w *WorldType, x, y int) material.ColourTuple {
+		return c.sampleAt(w, x, y, i, offsets)
+	}
+}
+
+// add accumulates the pixels of the tile that include accepts, returning how
+// many there were.
+func (r *refinement) add(result TileResult, include func(x, y int) bool) int {
+	added := 0
+	for i, colour := range result.Pixels {
+		x, y := result.Tile.X+i%result.Tile.Width, result.Tile.Y+i/result.Tile.Width
+		if include != nil && !include(x, y) {
+			continue
+		}
+
+		r.sum[y*r.width+x] = r.sum[y*r.width+x].Add(colour)
+		r.count[y*r.width+x]++
+		added++
+	}
+
+	return added
+}
+
+// colour returns the average of the samples taken for x, y. Pixels with no
+// samples yet take the colour of the nearest anchor of a coarser pass.
+func (r *refinement) colour(x, y int) material.ColourTuple {
+	if n := r.count[y*r.width+x]; n > 0 {
+		return r.sum[y*r.width+x].Div(float64(n))
+	}
+
+	for i := len(progressiveStrides) - 1; i >= 0; i-- {
+		stride := progressiveStrides[i]
+		ax, ay := x-x%stride, y-y%stride
+		if n := r.count[ay*r.width+ax]; n > 0 {
+			return r.sum[ay*r.width+ax].Div(float64(n))
+		}
+	}
+
+	return material.Colour(0, 0, 0)
+}
+
+// tile returns the current colours of the tile, row by row.
+func (r *refinement) tile(t Tile) []material.ColourTuple {
+	pixels := make([]material.ColourTuple, 0, t.Width*t.Height)
+	for y := t.Y; y < t.Y+t.Height; y++ {
+		for x := t.X; x < t.X+t.Width; x++ {
+			pixels = append(pixels, r.colour(x, y))
+		}
+	}
+
+	return pixels
+}
+
+func (r *refinement) image() CanvasType {
+	image := Canvas(r.width, r.height)
+	for y := 0; y < r.height; y++ {
+		for x := 0; x < r.width; x++ {
+			image.WritePixel(x, y, r.colour(x, y))
+		}
+	}
+
+	return image
+}
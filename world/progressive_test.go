@@ -0,0 +1,115 @@
+package world
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dannyroes/raytrace/material"
+)
+
+func TestRenderProgressiveMatchesRender(t *testing.T) {
+	for _, supersample := range []int{1, 2, 3} {
+		c, w := traceTestScene()
+		c.HSize, c.VSize = 19, 13
+		c.Supersample = supersample
+		c.TileSize = 5
+		c.CalcPixelSize()
+
+		expected := c.Render(w)
+
+		image, err := c.RenderProgressive(context.Background(), w, 0, nil)
+		if err != nil {
+			t.Fatalf("Unexpected error %v", err)
+		}
+
+		if image.Width != 19 || image.Height != 13 {
+			t.Fatalf("Size mismatch expected 19x13 received %dx%d", image.Width, image.Height)
+		}
+
+		for x := 0; x < c.HSize; x++ {
+			for y := 0; y < c.VSize; y++ {
+				if !material.ColourEqual(image.Pixel(x, y), expected.Pixel(x, y)) {
+					t.Fatalf("Supersample %d: pixel %d,%d mismatch expected %+v received %+v", supersample, x, y, expected.Pixel(x, y), image.Pixel(x, y))
+				}
+			}
+		}
+	}
+}
+
+func TestRenderProgressiveSnapshots(t *testing.T) {
+	c, w := traceTestScene()
+	c.HSize, c.VSize = 16, 16
+	c.Supersample = 2
+	c.TileSize = 16
+	c.CalcPixelSize()
+	w.Stats = &RayStats{}
+
+	obs := &recordingObserver{}
+	c.Observer = obs
+	c.ProgressInterval = time.Nanosecond
+
+	var snapshots []CanvasType
+	_, err := c.RenderProgressive(context.Background(), w, 0, func(image CanvasType) {
+		snapshots = append(snapshots, image)
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	// Four coarse passes then three more samples per pixel, one tile each.
+	if len(snapshots) != 7 {
+		t.Fatalf("Snapshot count mismatch expected %d received %d", 7, len(snapshots))
+	}
+
+	// After the first pass only every 8th pixel is sampled and the rest
+	// copy it.
+	first := snapshots[0]
+	for x := 0; x < 8; x++ {
+		for y := 0; y < 8; y++ {
+			if first.Pixel(x, y) != first.Pixel(0, 0) {
+				t.Fatalf("Expected pixel %d,%d filled from 0,0, received %+v and %+v", x, y, first.Pixel(x, y), first.Pixel(0, 0))
+			}
+		}
+	}
+
+	if w.Stats.Primary != 16*16*4 {
+		t.Errorf("Primary ray mismatch expected %d received %d", 16*16*4, w.Stats.Primary)
+	}
+
+	if len(obs.finished) != 1 || obs.finished[0].Pixels != 16*16*4 {
+		t.Errorf("Expected a finish event for every sample, received %+v", obs.finished)
+	}
+
+	if len(obs.started) != 1 || obs.started[0].Width != 16 || obs.started[0].Tiles != 7 {
+		t.Errorf("Start event mismatch received %+v", obs.started)
+	}
+}
+
+func TestRenderProgressiveCancelled(t *testing.T) {
+	c, w := traceTestScene()
+	c.HSize, c.VSize = 16, 16
+	c.Supersample = 2
+	c.TileSize = 16
+	c.Workers = 1
+	c.CalcPixelSize()
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	passes := 0
+	image, err := c.RenderProgressive(ctx, w, 0, func(image CanvasType) {
+		passes++
+		if passes == 4 {
+			cancel()
+		}
+	})
+	if err != context.Canceled {
+		t.Fatalf("Expected context.Canceled, received %v", err)
+	}
+
+	// Stopping after the coarse passes leaves one sample in every pixel.
+	expected := c.Render(w)
+	if image.Pixel(8, 8) == (material.ColourTuple{}) || image.Pixel(8, 8) == expected.Pixel(8, 8) {
+		t.Errorf("Expected a partly refined pixel, received %+v", image.Pixel(8, 8))
+	}
+}
@@ -0,0 +1,129 @@
+package world
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Region is a rectangle of the image, in pixels from its top left corner.
+// A camera with a Region only traces the rays inside it, producing an image
+// of just that part of the frame with the same pixels a full render has
+// there.
+type Region struct {
+	X      int
+	Y      int
+	Width  int
+	Height int
+}
+
+func (r Region) String() string {
+	return fmt.Sprintf("%d,%d,%d,%d", r.X, r.Y, r.Width, r.Height)
+}
+
+// ParseRegion reads a region as x,y,width,height for a width by height
+// image. Whole numbers are pixels; if any value has a decimal point they
+// are all fractions of the image size instead, so 0.5,0,0.5,1.0 is the
+// right half of the image. Fractional regions are rounded out to whole
+// pixels.
+func ParseRegion(s string, width, height int) (Region, error) {
+	parts := strings.Split(s, ",")
+	if len(parts) != 4 {
+		return Region{}, fmt.Errorf("invalid region %q, expected x,y,width,height", s)
+	}
+
+	var values [4]float64
+	for i, part := range parts {
+		v, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil {
+			return Region{}, fmt.Errorf("invalid region %q, expected x,y,width,height", s)
+		}
+		values[i] = v
+	}
+
+	var r Region
+	if strings.Contains(s, ".") {
+		x0 := math.Floor(values[0] * float64(width))
+		y0 := math.Floor(values[1] * float64(height))
+		x1 := math.Ceil((values[0] + values[2]) * float64(width))
+		y1 := math.Ceil((values[1] + values[3]) * float64(height))
+		r = Region{int(x0), int(y0), int(x1 - x0), int(y1 - y0)}
+	} else {
+		for _, v := range values {
+			if v != math.Trunc(v) {
+				return Region{}, fmt.Errorf("invalid region %q, expected whole pixels", s)
+			}
+		}
+		r = Region{int(values[0]), int(values[1]), int(values[2]), int(values[3])}
+	}
+
+	return r, r.Validate(width, height)
+}
+
+// Validate checks that the region is not empty and lies within a width by
+// height image.
+func (r Region) Validate(width, height int) error {
+	if r.Width <= 0 || r.Height <= 0 {
+		return fmt.Errorf("region %v is empty", r)
+	}
+	if r.X < 0 || r.Y < 0 || r.X+r.Width > width || r.Y+r.Height > height {
+		return fmt.Errorf("region %v is outside the %dx%d image", r, width, height)
+	}
+
+	return nil
+}
+
+// Scale maps the region of a width by height image onto the same part of
+// the image resized to newWidth by newHeight, rounding out to whole pixels.
+func (r Region) Scale(width, height, newWidth, newHeight int) Region {
+	sx := float64(newWidth) / float64(width)
+	sy := float64(newHeight) / float64(height)
+
+	x0 := math.Min(math.Floor(float64(r.X)*sx), float64(newWidth-1))
+	y0 := math.Min(math.Floor(float64(r.Y)*sy), float64(newHeight-1))
+	x1 := math.Max(x0+1, math.Min(math.Ceil(float64(r.X+r.Width)*sx), float64(newWidth)))
+	y1 := math.Max(y0+1, math.Min(math.Ceil(float64(r.Y+r.Height)*sy), float64(newHeight)))
+
+	return Region{int(x0), int(y0), int(x1 - x0), int(y1 - y0)}
+}
+
+// grow widens the region by n pixels on every side, keeping it within a
+// width by height image.
+func (r Region) grow(n, width, height int) Region {
+	x0 := int(math.Max(0, float64(r.X-n)))
+	y0 := int(math.Max(0, float64(r.Y-n)))
+	x1 := int(math.Min(float64(width), float64(r.X+r.Width+n)))
+	y1 := int(math.Min(float64(height), float64(r.Y+r.Height+n)))
+
+	return Region{x0, y0, x1 - x0, y1 - y0}
+}
+
+// ImageSize returns the size of the image the camera renders, the size of
+// its Region if it has one.
+func (c *CameraType) ImageSize() (int, int) {
+	if c.Region != nil {
+		return c.Region.Width, c.Region.Height
+	}
+
+	return c.HSize, c.VSize
+}
+
+// checkRegion validates the camera's Region, if it has one.
+func (c *CameraType) checkRegion() error {
+	if c.Region == nil {
+		return nil
+	}
+
+	return c.Region.Validate(c.HSize, c.VSize)
+}
+
+// regionOrigin returns where the image the camera renders starts in the
+// full frame.
+func (c *CameraType) regionOrigin() (int, int) {
+	if c.Region != nil {
+		return c.Region.X, c.Region.Y
+	}
+
+	return 0, 0
+}
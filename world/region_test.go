@@ -0,0 +1,151 @@
+package world
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dannyroes/raytrace/material"
+	"github.com/dannyroes/raytrace/sampler"
+)
+
+func TestParseRegion(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected Region
+	}{
+		{"2,3,4,5", Region{2, 3, 4, 5}},
+		{"0,0,1,1", Region{0, 0, 1, 1}},
+		{"0.5,0,0.5,1.0", Region{50, 0, 50, 80}},
+		{"0.25, 0.25, 0.5, 0.5", Region{25, 20, 50, 40}},
+		{"0.333,0,0.333,0.1", Region{33, 0, 34, 8}},
+	}
+
+	for _, test := range tests {
+		r, err := ParseRegion(test.input, 100, 80)
+		if err != nil {
+			t.Errorf("%q: unexpected error %v", test.input, err)
+			continue
+		}
+		if r != test.expected {
+			t.Errorf("%q: expected %v received %v", test.input, test.expected, r)
+		}
+	}
+
+	for _, input := range []string{"1,2,3", "a,b,c,d", "0,0,0,5", "90,0,20,10", "-1,0,5,5", "0,0,1.5,2", "0.5,0.5,0.6,0.1"} {
+		_, err := ParseRegion(input, 100, 80)
+		if err == nil {
+			t.Errorf("%q: expected error", input)
+		}
+	}
+}
+
+// regionMatches renders the region with c and checks every pixel against
+// the same part of full.
+func regionMatches(t *testing.T, name string, c *CameraType, w WorldType, full CanvasType, r Region) {
+	t.Helper()
+
+	c.Region = &r
+	image, err := c.RenderContext(context.Background(), w)
+	c.Region = nil
+	if err != nil {
+		t.Fatalf("%s: unexpected error %v", name, err)
+	}
+
+	if image.Width != r.Width || image.Height != r.Height {
+		t.Fatalf("%s: size mismatch expected %dx%d received %dx%d", name, r.Width, r.Height, image.Width, image.Height)
+	}
+
+	for x := 0; x < r.Width; x++ {
+		for y := 0; y < r.Height; y++ {
+			if !material.ColourEqual(image.Pixel(x, y), full.Pixel(r.X+x, r.Y+y)) {
+				t.Fatalf("%s: pixel %d,%d mismatch expected %+v received %+v", name, x, y, full.Pixel(r.X+x, r.Y+y), image.Pixel(x, y))
+			}
+		}
+	}
+}
+
+func TestRegionScale(t *testing.T) {
+	tests := []struct {
+		region   Region
+		width    int
+		height   int
+		expected Region
+	}{
+		{Region{50, 40, 50, 40}, 50, 40, Region{25, 20, 25, 20}},
+		{Region{25, 0, 10, 80}, 50, 40, Region{12, 0, 6, 40}},
+		{Region{99, 79, 1, 1}, 10, 8, Region{9, 7, 1, 1}},
+		{Region{10, 10, 20, 20}, 200, 160, Region{20, 20, 40, 40}},
+	}
+
+	for _, test := range tests {
+		r := test.region.Scale(100, 80, test.width, test.height)
+		if r != test.expected {
+			t.Errorf("%v at %dx%d: expected %v received %v", test.region, test.width, test.height, test.expected, r)
+		}
+		err := r.Validate(test.width, test.height)
+		if err != nil {
+			t.Errorf("%v at %dx%d: %v", test.region, test.width, test.height, err)
+		}
+	}
+}
+
+func TestRenderRegionMatchesFullRender(t *testing.T) {
+	r := Region{3, 2, 5, 6}
+
+	c, w := traceTestScene()
+	c.TileSize = 4
+	c.Supersample = 2
+	regionMatches(t, "supersample", c, w, c.Render(w), r)
+
+	c.Sampler = sampler.New(sampler.KindHalton, 4, 7)
+	regionMatches(t, "sampler", c, w, c.Render(w), r)
+
+	c.Filter = &Filter{Kind: FilterMitchell}
+	regionMatches(t, "filter", c, w, c.Render(w), r)
+
+	c.Filter = nil
+	c.Adaptive = &AdaptiveSampling{MinSamples: 2, MaxSamples: 8}
+	regionMatches(t, "adaptive", c, w, c.Render(w), r)
+}
+
+func TestRenderRegionTracesOnlyTheRegion(t *testing.T) {
+	c, w := traceTestScene()
+	c.Region = &Region{0, 0, 2, 3}
+	w.Stats = &RayStats{}
+
+	c.Render(w)
+	if w.Stats.Primary != 6 {
+		t.Errorf("Expected 6 primary rays, received %d", w.Stats.Primary)
+	}
+}
+
+func TestRenderProgressiveRegion(t *testing.T) {
+	c, w := traceTestScene()
+	c.Supersample = 2
+	full := c.Render(w)
+
+	r := Region{4, 1, 6, 7}
+	c.Region = &r
+	image, err := c.RenderProgressive(context.Background(), w, 0, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	for x := 0; x < r.Width; x++ {
+		for y := 0; y < r.Height; y++ {
+			if !material.ColourEqual(image.Pixel(x, y), full.Pixel(r.X+x, r.Y+y)) {
+				t.Fatalf("Pixel %d,%d mismatch expected %+v received %+v", x, y, full.Pixel(r.X+x, r.Y+y), image.Pixel(x, y))
+			}
+		}
+	}
+}
+
+func TestRenderRegionOutsideImage(t *testing.T) {
+	c, w := traceTestScene()
+	c.Region = &Region{8, 8, 5, 5}
+
+	_, err := c.RenderContext(context.Background(), w)
+	if err == nil {
+		t.Error("Expected error rendering a region outside the image")
+	}
+}
@@ -0,0 +1,89 @@
+package world
+
+import (
+	"errors"
+	"math"
+
+	"github.com/dannyroes/raytrace/data"
+	"github.com/dannyroes/raytrace/material"
+	"github.com/dannyroes/raytrace/shape"
+)
+
+const StudioFieldOfView = math.Pi / 4
+
+// Studio scales and centres obj so its largest side is two units long and it
+// rests on a checkered ground plane at y = 0, lights it with a key, fill and
+// back light, and returns a camera looking at it from the front three
+// quarters. obj must have finite bounds.
+func Studio(obj shape.Shape, hsize, vsize int) (*CameraType, WorldType, error) {
+	w := World()
+
+	b := shape.TransformBounds(obj.Bounds(), obj.GetTransform())
+	if b.IsEmpty() || !finiteBounds(b) {
+		return nil, w, errors.New("object has no finite bounds to frame")
+	}
+
+	size := data.FloatMax(b.Max.X-b.Min.X, b.Max.Y-b.Min.Y, b.Max.Z-b.Min.Z)
+	scale := 1.0
+	if size > 0 {
+		scale = 2 / size
+	}
+
+	centreX := (b.Min.X + b.Max.X) / 2
+	centreZ := (b.Min.Z + b.Max.Z) / 2
+	obj.SetTransform(obj.GetTransform().Translate(-centreX, -b.Min.Y, -centreZ).Scale(scale, scale, scale))
+
+	height := (b.Max.Y - b.Min.Y) * scale
+	radius := b.Max.Sub(b.Min).Magnitude() * scale / 2
+	if radius == 0 {
+		radius = 1
+	}
+	target := data.Point(0, height/2, 0)
+
+	c := Camera(hsize, vsize, StudioFieldOfView)
+
+	// Fit the bounding sphere inside the narrower of the two view angles.
+	aspect := float64(hsize) / float64(vsize)
+	halfAngle := math.Atan(math.Tan(StudioFieldOfView/2) * math.Min(aspect, 1/aspect))
+	distance := radius / math.Sin(halfAngle) * 1.05
+
+	up := data.Vector(0, 1, 0)
+	viewDir := data.Vector(0.6, 0.45, -1).Normalize()
+	from := target.Add(viewDir.Mul(distance))
+	c.Transform = data.ViewTransform(from, target, up)
+
+	forward := target.Sub(from).Normalize()
+	side := data.Cross(forward, up).Normalize()
+
+	key := from.Add(side.Mul(distance * 0.6)).Add(up.Mul(distance * 0.8))
+	fill := from.Sub(side.Mul(distance * 0.8)).Add(up.Mul(distance * 0.1))
+	back := target.Add(forward.Mul(distance)).Add(up.Mul(distance))
+
+	w.Lights = []Light{
+		PointLight(key, material.Colour(0.7, 0.7, 0.7)),
+		PointLight(fill, material.Colour(0.3, 0.3, 0.3)),
+		PointLight(back, material.Colour(0.4, 0.4, 0.4)),
+	}
+
+	floor := shape.Plane()
+	m := material.Material()
+	m.Pattern = material.CheckersPattern(material.Colour(0.8, 0.8, 0.8), material.Colour(0.55, 0.55, 0.55))
+	m.Pattern.SetTransform(data.Scaling(0.5, 0.5, 0.5))
+	m.Ambient = 0.05
+	m.Specular = 0
+	m.Reflective = 0.1
+	floor.SetMaterial(m)
+
+	w.Objects = []shape.Shape{floor, obj}
+
+	return c, w, nil
+}
+
+func finiteBounds(b shape.Bounds) bool {
+	for _, v := range []float64{b.Min.X, b.Min.Y, b.Min.Z, b.Max.X, b.Max.Y, b.Max.Z} {
+		if math.IsInf(v, 0) || math.IsNaN(v) {
+			return false
+		}
+	}
+	return true
+}
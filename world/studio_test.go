@@ -0,0 +1,55 @@
+package world
+
+import (
+	"testing"
+
+	"github.com/dannyroes/raytrace/data"
+	"github.com/dannyroes/raytrace/shape"
+)
+
+func TestStudio(t *testing.T) {
+	s := shape.Sphere()
+	s.SetTransform(data.Scaling(10, 5, 10).Translate(100, -20, 3))
+
+	c, w, err := Studio(s, 40, 30)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	b := shape.TransformBounds(s.Bounds(), s.GetTransform())
+	expected := shape.Bounds{Min: data.Point(-1, 0, -1), Max: data.Point(1, 1, 1)}
+	if !data.TupleEqual(b.Min, expected.Min) || !data.TupleEqual(b.Max, expected.Max) {
+		t.Errorf("Bounds mismatch expected %+v received %+v", expected, b)
+	}
+
+	if len(w.Lights) != 3 {
+		t.Errorf("Light count mismatch expected %d received %d", 3, len(w.Lights))
+	}
+
+	if len(w.Objects) != 2 || w.Objects[1] != s {
+		t.Errorf("Expected ground plane and object, received %+v", w.Objects)
+	}
+
+	if c.HSize != 40 || c.VSize != 30 {
+		t.Errorf("Camera size mismatch expected 40x30 received %dx%d", c.HSize, c.VSize)
+	}
+
+	for _, p := range [][2]int{{20, 15}, {0, 0}, {39, 29}} {
+		r := c.RayForPixel(p[0], p[1])
+		hit := w.Intersect(r).Hit()
+		centre := p[0] == 20
+		if centre && hit.Object != s {
+			t.Errorf("Expected the centre pixel to see the object, received %+v", hit.Object)
+		}
+		if !centre && hit.Object == s {
+			t.Errorf("Expected corner pixel %v to miss the object", p)
+		}
+	}
+}
+
+func TestStudioUnbounded(t *testing.T) {
+	_, _, err := Studio(shape.Plane(), 40, 30)
+	if err == nil {
+		t.Error("Expected error framing an infinite plane")
+	}
+}
@@ -0,0 +1,283 @@
+package world
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/dannyroes/raytrace/material"
+)
+
+// tileFileMagic starts every tile file, followed by the width and height.
+var tileFileMagic = []byte("RTTILES1")
+
+const tileFileHeader = 8 + 4 + 4
+
+// tileFilePixel is the size of a pixel in a tile file, three float64s kept
+// at full precision so the image encodes exactly as it would from memory.
+const tileFilePixel = 24
+
+// TileFile keeps an image on disk as rows of float64 red, green and blue. A
+// render can write its tiles there as they finish, see RenderTo, and the
+// image can then be encoded a row at a time, so neither step needs the
+// whole image in memory.
+type TileFile struct {
+	Width  int
+	Height int
+	f      *os.File
+}
+
+// CreateTileFile creates a black width by height image in filename.
+func CreateTileFile(filename string, width, height int) (*TileFile, error) {
+	f, err := os.Create(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	header := make([]byte, tileFileHeader)
+	copy(header, tileFileMagic)
+	binary.BigEndian.PutUint32(header[8:], uint32(width))
+	binary.BigEndian.PutUint32(header[12:], uint32(height))
+
+	_, err = f.Write(header)
+	if err == nil {
+		err = f.Truncate(tileFileHeader + int64(width)*int64(height)*tileFilePixel)
+	}
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	return &TileFile{Width: width, Height: height, f: f}, nil
+}
+
+// OpenTileFile opens a tile file written by an earlier render.
+func OpenTileFile(filename string) (*TileFile, error) {
+	f, err := os.OpenFile(filename, os.O_RDWR, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	header := make([]byte, tileFileHeader)
+	_, err = io.ReadFull(f, header)
+	if err != nil || !bytes.Equal(header[:8], tileFileMagic) {
+		f.Close()
+		return nil, fmt.Errorf("%s: not a tile file", filename)
+	}
+
+	width := int(binary.BigEndian.Uint32(header[8:]))
+	height := int(binary.BigEndian.Uint32(header[12:]))
+
+	return &TileFile{Width: width, Height: height, f: f}, nil
+}
+
+func (t *TileFile) Close() error {
+	return t.f.Close()
+}
+
+func (t *TileFile) offset(x, y int) int64 {
+	return tileFileHeader + (int64(y)*int64(t.Width)+int64(x))*tileFilePixel
+}
+
+// WriteTile stores the tile's pixels, one row of the tile at a time.
+func (t *TileFile) WriteTile(r TileResult) error {
+	if r.Tile.X < 0 || r.Tile.Y < 0 || r.Tile.X+r.Tile.Width > t.Width || r.Tile.Y+r.Tile.Height > t.Height {
+		return fmt.Errorf("tile %+v is outside the %dx%d image", r.Tile, t.Width, t.Height)
+	}
+
+	row := make([]byte, r.Tile.Width*tileFilePixel)
+	for y := 0; y < r.Tile.Height; y++ {
+		for x := 0; x < r.Tile.Width; x++ {
+			putColour(row[x*tileFilePixel:], r.Pixels[y*r.Tile.Width+x])
+		}
+
+		_, err := t.f.WriteAt(row, t.offset(r.Tile.X, r.Tile.Y+y))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ReadRow reads row y of the image into row, which must hold Width colours.
+func (t *TileFile) ReadRow(y int, row []material.ColourTuple) error {
+	buf := make([]byte, t.Width*tileFilePixel)
+	_, err := t.f.ReadAt(buf, t.offset(0, y))
+	if err != nil {
+		return err
+	}
+
+	for x := range row[:t.Width] {
+		row[x] = getColour(buf[x*tileFilePixel:])
+	}
+
+	return nil
+}
+
+func putColour(b []byte, c material.ColourTuple) {
+	binary.LittleEndian.PutUint64(b, math.Float64bits(c.Red()))
+	binary.LittleEndian.PutUint64(b[8:], math.Float64bits(c.Green()))
+	binary.LittleEndian.PutUint64(b[16:], math.Float64bits(c.Blue()))
+}
+
+func getColour(b []byte) material.ColourTuple {
+	return material.Colour(
+		math.Float64frombits(binary.LittleEndian.Uint64(b)),
+		math.Float64frombits(binary.LittleEndian.Uint64(b[8:])),
+		math.Float64frombits(binary.LittleEndian.Uint64(b[16:])),
+	)
+}
+
+// WritePNG encodes the image as an 8 bit RGB PNG a row at a time, adding
+// each entry of meta as a text chunk like ToPNGWithMetadata.
+func (t *TileFile) WritePNG(w io.Writer, meta map[string]string) error {
+	ihdr := make([]byte, 13)
+	binary.BigEndian.PutUint32(ihdr, uint32(t.Width))
+	binary.BigEndian.PutUint32(ihdr[4:], uint32(t.Height))
+	// bit depth 8, colour type 2 for RGB, then the default compression,
+	// filter and no interlacing.
+	ihdr[8], ihdr[9] = 8, 2
+
+	_, err := w.Write(pngSignature)
+	if err == nil {
+		err = writeChunk(w, "IHDR", ihdr)
+	}
+	if err == nil {
+		err = writeTextChunks(w, meta)
+	}
+	if err != nil {
+		return err
+	}
+
+	idat := bufio.NewWriterSize(chunkWriter{w, "IDAT"}, 1<<16)
+	zw := zlib.NewWriter(idat)
+
+	row := make([]material.ColourTuple, t.Width)
+	current := make([]byte, t.Width*3)
+	previous := make([]byte, t.Width*3)
+	filtered := make([]byte, t.Width*3+1)
+
+	for y := 0; y < t.Height; y++ {
+		err = t.ReadRow(y, row)
+		if err != nil {
+			return err
+		}
+
+		for x, colour := range row {
+			r, g, b, _ := colour.RGBA()
+			current[x*3], current[x*3+1], current[x*3+2] = uint8(r>>8), uint8(g>>8), uint8(b>>8)
+		}
+
+		paethFilter(filtered, current, previous, 3)
+		_, err = zw.Write(filtered)
+		if err != nil {
+			return err
+		}
+
+		current, previous = previous, current
+	}
+
+	err = zw.Close()
+	if err == nil {
+		err = idat.Flush()
+	}
+	if err == nil {
+		err = writeChunk(w, "IEND", nil)
+	}
+
+	return err
+}
+
+// chunkWriter writes everything written to it as chunks of one kind.
+type chunkWriter struct {
+	w    io.Writer
+	kind string
+}
+
+func (c chunkWriter) Write(p []byte) (int, error) {
+	err := writeChunk(c.w, c.kind, p)
+	if err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
+
+// paethFilter writes the filter type byte and then current filtered against
+// previous with the PNG Paeth predictor, bpp bytes to a pixel.
+func paethFilter(out, current, previous []byte, bpp int) {
+	out[0] = 4
+	for i := range current {
+		var a, c int
+		b := int(previous[i])
+		if i >= bpp {
+			a = int(current[i-bpp])
+			c = int(previous[i-bpp])
+		}
+
+		p := a + b - c
+		pa, pb, pc := abs(p-a), abs(p-b), abs(p-c)
+
+		predictor := c
+		if pa <= pb && pa <= pc {
+			predictor = a
+		} else if pb <= pc {
+			predictor = b
+		}
+
+		out[i+1] = current[i] - byte(predictor)
+	}
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+
+	return x
+}
+
+// WritePPM encodes the image as a plain PPM a row at a time, the same as
+// ToPPM.
+func (t *TileFile) WritePPM(w io.Writer) error {
+	_, err := fmt.Fprintf(w, "P3\n%d %d\n255\n", t.Width, t.Height)
+	if err != nil {
+		return err
+	}
+
+	var pixels strings.Builder
+	var line strings.Builder
+	row := make([]material.ColourTuple, t.Width)
+
+	for y := 0; y < t.Height; y++ {
+		err = t.ReadRow(y, row)
+		if err != nil {
+			return err
+		}
+
+		for _, colour := range row {
+			writePixelValue(&pixels, &line, strconv.Itoa(material.GetCappedColour(colour.Red(), 255)))
+			writePixelValue(&pixels, &line, strconv.Itoa(material.GetCappedColour(colour.Green(), 255)))
+			writePixelValue(&pixels, &line, strconv.Itoa(material.GetCappedColour(colour.Blue(), 255)))
+		}
+		line.WriteString("\n")
+		pixels.WriteString(line.String())
+		line.Reset()
+
+		_, err = io.WriteString(w, pixels.String())
+		if err != nil {
+			return err
+		}
+		pixels.Reset()
+	}
+
+	return nil
+}
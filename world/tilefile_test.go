@@ -0,0 +1,177 @@
+package world
+
+import (
+	"bytes"
+	"context"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dannyroes/raytrace/material"
+)
+
+func TestRenderToTileFile(t *testing.T) {
+	dir := t.TempDir()
+
+	c, w := traceTestScene()
+	c.HSize, c.VSize = 23, 17
+	c.TileSize = 5
+	c.Supersample = 2
+	c.CalcPixelSize()
+	expected := c.Render(w)
+
+	tf, err := CreateTileFile(filepath.Join(dir, "render.tiles"), c.HSize, c.VSize)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	defer tf.Close()
+
+	err = c.RenderTo(context.Background(), w, tf)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	row := make([]material.ColourTuple, c.HSize)
+	for y := 0; y < c.VSize; y++ {
+		err = tf.ReadRow(y, row)
+		if err != nil {
+			t.Fatalf("Unexpected error %v", err)
+		}
+		for x := range row {
+			if row[x] != expected.Pixel(x, y) {
+				t.Fatalf("Pixel %d,%d mismatch expected %+v received %+v", x, y, expected.Pixel(x, y), row[x])
+			}
+		}
+	}
+
+	var ppm bytes.Buffer
+	err = tf.WritePPM(&ppm)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if ppm.String() != expected.ToPPM() {
+		t.Error("Expected the streamed PPM to match ToPPM")
+	}
+
+	filename := filepath.Join(dir, "render.png")
+	f, err := os.Create(filename)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	err = tf.WritePNG(f, map[string]string{"Scene": "trace.yml"})
+	f.Close()
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	image, err := LoadPNG(filename)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	res, err := Compare(expected.Quantize(), image)
+	if err != nil || res.Differing != 0 {
+		t.Errorf("Expected the streamed PNG to match the render, %d pixels differ, %v", res.Differing, err)
+	}
+
+	meta, err := ReadPNGMetadata(filename)
+	if err != nil || meta["Scene"] != "trace.yml" {
+		t.Errorf("Expected metadata in the streamed PNG, received %v, %v", meta, err)
+	}
+}
+
+func TestTileFileStreamedPNGDecodes(t *testing.T) {
+	tf, err := CreateTileFile(filepath.Join(t.TempDir(), "image.tiles"), 300, 2)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	defer tf.Close()
+
+	// Varied pixels so every Paeth predictor is used.
+	pixels := make([]material.ColourTuple, 300)
+	for i := range pixels {
+		pixels[i] = material.Colour(float64(i%7)/7, float64(i%13)/13, float64(i%3)/3)
+	}
+	err = tf.WriteTile(TileResult{Tile: Tile{0, 1, 300, 1}, Pixels: pixels})
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	var buf bytes.Buffer
+	err = tf.WritePNG(&buf, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	decoded, err := png.Decode(&buf)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	for x := 0; x < 300; x++ {
+		er, eg, eb, _ := pixels[x].RGBA()
+		r, g, b, _ := decoded.At(x, 1).RGBA()
+		if er>>8 != r>>8 || eg>>8 != g>>8 || eb>>8 != b>>8 {
+			t.Fatalf("Pixel %d mismatch expected %d,%d,%d received %d,%d,%d", x, er>>8, eg>>8, eb>>8, r>>8, g>>8, b>>8)
+		}
+	}
+
+	if r, g, b, _ := decoded.At(5, 0).RGBA(); r != 0 || g != 0 || b != 0 {
+		t.Errorf("Expected the unwritten row to be black, received %d,%d,%d", r, g, b)
+	}
+}
+
+func TestOpenTileFile(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "image.tiles")
+
+	tf, err := CreateTileFile(filename, 4, 3)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	colour := material.Colour(0.25, 0.5, 0.75)
+	err = tf.WriteTile(TileResult{Tile: Tile{2, 1, 1, 1}, Pixels: []material.ColourTuple{colour}})
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	err = tf.WriteTile(TileResult{Tile: Tile{3, 2, 2, 2}, Pixels: make([]material.ColourTuple, 4)})
+	if err == nil {
+		t.Error("Expected error writing a tile outside the image")
+	}
+	tf.Close()
+
+	tf, err = OpenTileFile(filename)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	defer tf.Close()
+
+	row := make([]material.ColourTuple, 4)
+	err = tf.ReadRow(1, row)
+	if err != nil || tf.Width != 4 || tf.Height != 3 || row[2] != colour {
+		t.Errorf("Expected a 4x3 image with the pixel written, received %dx%d %+v, %v", tf.Width, tf.Height, row, err)
+	}
+
+	other := filepath.Join(t.TempDir(), "image.ppm")
+	err = os.WriteFile(other, []byte(Canvas(4, 3).ToPPM()), 0644)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	_, err = OpenTileFile(other)
+	if err == nil {
+		t.Error("Expected error opening a file that is not a tile file")
+	}
+}
+
+func TestRenderToUnsupported(t *testing.T) {
+	c, w := traceTestScene()
+	c.Filter = &Filter{Kind: FilterTent}
+
+	err := c.RenderTo(context.Background(), w, discardTiles{})
+	if err == nil {
+		t.Error("Expected error rendering a filtered image to tiles")
+	}
+}
@@ -0,0 +1,135 @@
+package world
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// DefaultTileSize is the side of the square tiles an image is split into
+// when CameraType.TileSize is not set.
+const DefaultTileSize = 16
+
+type TileOrder int
+
+const (
+	TileScanline TileOrder = iota
+	TileSpiral
+	TileHilbert
+)
+
+var tileOrders = []TileOrder{TileScanline, TileSpiral, TileHilbert}
+
+func (o TileOrder) String() string {
+	switch o {
+	case TileScanline:
+		return "scanline"
+	case TileSpiral:
+		return "spiral"
+	case TileHilbert:
+		return "hilbert"
+	}
+
+	return "unknown"
+}
+
+func ParseTileOrder(name string) (TileOrder, error) {
+	for _, o := range tileOrders {
+		if o.String() == name {
+			return o, nil
+		}
+	}
+
+	return TileScanline, fmt.Errorf("unknown tile order %q, expected scanline, spiral or hilbert", name)
+}
+
+// Tile is a rectangle of pixels rendered as one job. Tiles on the right and
+// bottom edges may be smaller than the rest.
+type Tile struct {
+	X      int
+	Y      int
+	Width  int
+	Height int
+}
+
+// Tiles splits a width by height image into tiles of size pixels square,
+// returned in the given order. Spiral starts at the tile nearest the centre
+// and works outwards ring by ring; Hilbert follows a Hilbert curve so that
+// consecutive tiles are always neighbours.
+func Tiles(width, height, size int, order TileOrder) []Tile {
+	if size <= 0 {
+		size = DefaultTileSize
+	}
+
+	columns := (width + size - 1) / size
+	rows := (height + size - 1) / size
+
+	tiles := make([]Tile, 0, columns*rows)
+	for ty := 0; ty < rows; ty++ {
+		for tx := 0; tx < columns; tx++ {
+			t := Tile{X: tx * size, Y: ty * size, Width: size, Height: size}
+			if t.X+t.Width > width {
+				t.Width = width - t.X
+			}
+			if t.Y+t.Height > height {
+				t.Height = height - t.Y
+			}
+			tiles = append(tiles, t)
+		}
+	}
+
+	switch order {
+	case TileSpiral:
+		cx, cy := float64(columns-1)/2, float64(rows-1)/2
+		key := func(t Tile) (float64, float64) {
+			dx := float64(t.X/size) - cx
+			dy := float64(t.Y/size) - cy
+			ring := math.Max(math.Abs(dx), math.Abs(dy))
+			return math.Round(ring), math.Atan2(dy, dx)
+		}
+		sort.SliceStable(tiles, func(i, j int) bool {
+			ri, ai := key(tiles[i])
+			rj, aj := key(tiles[j])
+			if ri != rj {
+				return ri < rj
+			}
+			return ai < aj
+		})
+	case TileHilbert:
+		n := 1
+		for n < columns || n < rows {
+			n *= 2
+		}
+		sort.SliceStable(tiles, func(i, j int) bool {
+			return hilbertIndex(n, tiles[i].X/size, tiles[i].Y/size) < hilbertIndex(n, tiles[j].X/size, tiles[j].Y/size)
+		})
+	}
+
+	return tiles
+}
+
+// hilbertIndex returns the distance along a Hilbert curve filling an n by n
+// grid, n being a power of two, to cell x, y.
+func hilbertIndex(n, x, y int) int {
+	d := 0
+	for s := n / 2; s > 0; s /= 2 {
+		rx, ry := 0, 0
+		if x&s > 0 {
+			rx = 1
+		}
+		if y&s > 0 {
+			ry = 1
+		}
+		d += s * s * ((3 * rx) ^ ry)
+
+		if ry == 0 {
+			if rx == 1 {
+				x = n - 1 - x
+				y = n - 1 - y
+			}
+			x, y = y, x
+		}
+	}
+
+	return d
+}
@@ -0,0 +1,105 @@
+package world
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dannyroes/raytrace/material"
+)
+
+func TestTilesCoverImage(t *testing.T) {
+	for _, order := range tileOrders {
+		tiles := Tiles(37, 21, 8, order)
+
+		if len(tiles) != 15 {
+			t.Errorf("%s: tile count mismatch expected %d received %d", order, 15, len(tiles))
+		}
+
+		covered := map[[2]int]int{}
+		for _, tile := range tiles {
+			for x := tile.X; x < tile.X+tile.Width; x++ {
+				for y := tile.Y; y < tile.Y+tile.Height; y++ {
+					covered[[2]int{x, y}]++
+				}
+			}
+		}
+
+		if len(covered) != 37*21 {
+			t.Errorf("%s: expected every pixel covered, received %d of %d", order, len(covered), 37*21)
+		}
+
+		for p, n := range covered {
+			if n != 1 || p[0] >= 37 || p[1] >= 21 {
+				t.Errorf("%s: pixel %v covered %d times", order, p, n)
+				break
+			}
+		}
+	}
+}
+
+func TestTilesSpiralStartsInCentre(t *testing.T) {
+	tiles := Tiles(50, 50, 10, TileSpiral)
+
+	if tiles[0].X != 20 || tiles[0].Y != 20 {
+		t.Errorf("Expected first tile at the centre, received %+v", tiles[0])
+	}
+
+	last := tiles[len(tiles)-1]
+	if last.X != 0 && last.X != 40 && last.Y != 0 && last.Y != 40 {
+		t.Errorf("Expected last tile on the edge, received %+v", last)
+	}
+}
+
+func TestTilesHilbertNeighbours(t *testing.T) {
+	tiles := Tiles(64, 64, 8, TileHilbert)
+
+	for i := 1; i < len(tiles); i++ {
+		dx := tiles[i].X - tiles[i-1].X
+		dy := tiles[i].Y - tiles[i-1].Y
+		if dx*dx+dy*dy != 64 {
+			t.Fatalf("Expected tile %d to neighbour the previous one, received %+v after %+v", i, tiles[i], tiles[i-1])
+		}
+	}
+}
+
+func TestParseTileOrder(t *testing.T) {
+	for _, o := range tileOrders {
+		parsed, err := ParseTileOrder(o.String())
+		if err != nil || parsed != o {
+			t.Errorf("Parse mismatch expected %s received %s, %v", o, parsed, err)
+		}
+	}
+
+	_, err := ParseTileOrder("zigzag")
+	if err == nil {
+		t.Error("Expected error for an unknown tile order")
+	}
+}
+
+func TestRenderTileOrdersMatch(t *testing.T) {
+	c, w := traceTestScene()
+	c.HSize, c.VSize = 23, 17
+	c.CalcPixelSize()
+
+	c.Workers = 1
+	expected := c.Render(w)
+
+	for _, order := range tileOrders {
+		c.TileOrder = order
+		c.TileSize = 5
+		c.Workers = 3
+
+		image, err := c.RenderContext(context.Background(), w)
+		if err != nil {
+			t.Fatalf("Unexpected error %v", err)
+		}
+
+		for x := 0; x < c.HSize; x++ {
+			for y := 0; y < c.VSize; y++ {
+				if !material.ColourEqual(image.Pixel(x, y), expected.Pixel(x, y)) {
+					t.Fatalf("%s: pixel %d,%d mismatch expected %+v received %+v", order, x, y, expected.Pixel(x, y), image.Pixel(x, y))
+				}
+			}
+		}
+	}
+}
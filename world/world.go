@@ -2,6 +2,7 @@ package world
 
 import (
 	"math"
+	"sync/atomic"
 
 	"github.com/dannyroes/raytrace/data"
 	"github.com/dannyroes/raytrace/material"
@@ -11,6 +12,19 @@ import (
 type WorldType struct {
 	Objects []shape.Shape
 	Lights  []Light
+	Stats   *RayStats
+}
+
+// RayStats counts the rays traced through a world. Primary rays come from
+// the camera, secondary rays from reflection and refraction.
+type RayStats struct {
+	Primary   uint64
+	Secondary uint64
+	Shadow    uint64
+}
+
+func (s *RayStats) Total() uint64 {
+	return atomic.LoadUint64(&s.Primary) + atomic.LoadUint64(&s.Secondary) + atomic.LoadUint64(&s.Shadow)
 }
 
 func World() WorldType {
@@ -84,6 +98,10 @@ func (w WorldType) ShadeHit(c shape.Computations, remain int) material.ColourTup
 }
 
 func (w WorldType) IsShadowed(p data.Tuple, lightIndex int) bool {
+	if w.Stats != nil {
+		atomic.AddUint64(&w.Stats.Shadow, 1)
+	}
+
 	v := w.Lights[lightIndex].Position.Sub(p)
 	distance := v.Magnitude()
 	direction := v.Normalize()
@@ -115,6 +133,10 @@ func (w WorldType) ReflectedColour(c shape.Computations, remain int) material.Co
 		return material.Black
 	}
 
+	if w.Stats != nil {
+		atomic.AddUint64(&w.Stats.Secondary, 1)
+	}
+
 	ray := data.Ray(c.OverPoint, c.ReflectV)
 	colour := w.ColourAt(ray, remain-1)
 
@@ -126,20 +148,34 @@ func (w WorldType) RefractedColour(c shape.Computations, remain int) material.Co
 		return material.Black
 	}
 
+	refractRay, ok := refractedRay(c)
+	if !ok {
+		return material.Black
+	}
+
+	if w.Stats != nil {
+		atomic.AddUint64(&w.Stats.Secondary, 1)
+	}
+
+	colour := w.ColourAt(refractRay, remain-1).Mul(c.Object.GetMaterial().Transparency)
+
+	return colour
+}
+
+// refractedRay bends the eye ray through the surface, returning false on
+// total internal reflection.
+func refractedRay(c shape.Computations) (data.RayType, bool) {
 	nRatio := c.N1 / c.N2
 	cosi := data.Dot(c.EyeV, c.NormalV)
 
 	sin2t := math.Pow(nRatio, 2) * (1 - math.Pow(cosi, 2))
 
 	if sin2t > 1 {
-		return material.Black
+		return data.RayType{}, false
 	}
 
 	cost := math.Sqrt(1.0 - sin2t)
 	dir := c.NormalV.Mul((nRatio * cosi) - cost).Sub(c.EyeV.Mul(nRatio))
 
-	refractRay := data.Ray(c.UnderPoint, dir)
-	colour := w.ColourAt(refractRay, remain-1).Mul(c.Object.GetMaterial().Transparency)
-
-	return colour
+	return data.Ray(c.UnderPoint, dir), true
 }
@@ -451,3 +451,32 @@ func TestShadeHitTransparentReflection(t *testing.T) {
 		t.Errorf("Colour mismatch expected %v received %v", material.Colour(0.93642, 0.68642, 0.68642), colour)
 	}
 }
+
+func TestRayStats(t *testing.T) {
+	w := DefaultWorld()
+	w.Stats = &RayStats{}
+
+	m := w.Objects[0].GetMaterial()
+	m.Reflective = 0.5
+	w.Objects[0].SetMaterial(m)
+
+	c := Camera(5, 5, math.Pi/2)
+	c.Transform = data.ViewTransform(data.Point(0, 0, -5), data.Point(0, 0, 0), data.Vector(0, 1, 0))
+	c.Render(w)
+
+	if w.Stats.Primary != 25 {
+		t.Errorf("Primary ray mismatch expected %d received %d", 25, w.Stats.Primary)
+	}
+
+	if w.Stats.Secondary == 0 {
+		t.Error("Expected reflections to cast secondary rays")
+	}
+
+	if w.Stats.Shadow < w.Stats.Secondary {
+		t.Errorf("Expected a shadow ray for every hit, received %d shadow and %d secondary", w.Stats.Shadow, w.Stats.Secondary)
+	}
+
+	if w.Stats.Total() != w.Stats.Primary+w.Stats.Secondary+w.Stats.Shadow {
+		t.Errorf("Total mismatch received %d", w.Stats.Total())
+	}
+}
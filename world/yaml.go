@@ -1,8 +1,12 @@
 package world
 
 import (
+	"crypto/sha256"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
+	"sort"
 
 	"github.com/dannyroes/raytrace/data"
 	"github.com/dannyroes/raytrace/material"
@@ -23,6 +27,7 @@ type SceneCamera struct {
 
 type SceneObject struct {
 	Type      string `mapstructure:"add"`
+	File      string
 	Material  *SceneMaterial
 	Transform [][]interface{}
 }
@@ -63,14 +68,9 @@ func LoadScene(filename string) (*CameraType, WorldType, error) {
 	w := World()
 	c := &CameraType{}
 	definitions := map[string]interface{}{}
+	dir := filepath.Dir(filename)
 
-	yamlScene, err := os.ReadFile(filename)
-	if err != nil {
-		return c, w, err
-	}
-	items := []map[string]interface{}{}
-
-	err = yaml.Unmarshal(yamlScene, &items)
+	items, err := readScene(filename)
 	if err != nil {
 		return c, w, err
 	}
@@ -81,33 +81,209 @@ func LoadScene(filename string) (*CameraType, WorldType, error) {
 			switch t {
 			case "camera":
 				c = processCamera(item)
-			case "sphere", "cube", "plane", "cylinder":
-				w.Objects = append(w.Objects, processObject(item))
+			case "sphere", "cube", "plane", "cylinder", "obj":
+				obj, err := processObject(item, dir)
+				if err != nil {
+					return c, w, err
+				}
+				w.Objects = append(w.Objects, obj)
 			case "light":
 				w.Lights = append(w.Lights, processLight(item))
 			}
-		} else if name, exists := item["define"]; exists {
-			var result interface{}
-
-			switch v := item["value"].(type) {
-			case map[interface{}]interface{}:
-				if ext, exists := item["extend"]; exists {
-					for key, value := range definitions[ext.(string)].(map[interface{}]interface{}) {
-						if _, exists := v[key]; !exists {
-							v[key] = value
-						}
+		} else if _, exists := item["define"]; exists {
+			processDefinition(item, definitions)
+		}
+	}
+
+	return c, w, nil
+}
+
+type NamedMaterial struct {
+	Name     string
+	Material material.MaterialType
+}
+
+// SceneMaterials returns every definition in the scene that describes a
+// material, in the order they were defined.
+func SceneMaterials(filename string) ([]NamedMaterial, error) {
+	var materials []NamedMaterial
+	definitions := map[string]interface{}{}
+
+	items, err := readScene(filename)
+	if err != nil {
+		return materials, err
+	}
+
+	for _, item := range items {
+		if _, exists := item["define"]; !exists {
+			continue
+		}
+
+		name, value := processDefinition(item, definitions)
+		if _, ok := value.(map[interface{}]interface{}); !ok {
+			continue
+		}
+
+		var result SceneMaterial
+		decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{ErrorUnused: true, Result: &result})
+		if err != nil {
+			return materials, err
+		}
+
+		if decoder.Decode(value) == nil {
+			materials = append(materials, NamedMaterial{name, processMaterial(result)})
+		}
+	}
+
+	return materials, nil
+}
+
+func processDefinition(item map[string]interface{}, definitions map[string]interface{}) (string, interface{}) {
+	var result interface{}
+	name := fmt.Sprint(item["define"])
+
+	switch v := item["value"].(type) {
+	case map[interface{}]interface{}:
+		if ext, exists := item["extend"]; exists {
+			if base, ok := definitions[fmt.Sprint(ext)].(map[interface{}]interface{}); ok {
+				for key, value := range base {
+					if _, exists := v[key]; !exists {
+						v[key] = value
 					}
 				}
-				result = v
-			case []interface{}:
-				result = mergeDefinitions(v, definitions)
 			}
+		}
+		result = v
+	case []interface{}:
+		result = mergeDefinitions(v, definitions)
+	}
+
+	definitions[name] = result
+	return name, result
+}
+
+// SceneFiles returns the scene file followed by every file it references,
+// such as OBJ models, so callers can tell when a scene needs reloading.
+func SceneFiles(filename string) ([]string, error) {
+	files := []string{filename}
 
-			definitions[name.(string)] = result
+	items, err := readScene(filename)
+	if err != nil {
+		return files, err
+	}
+
+	for _, item := range items {
+		if item["add"] != "obj" {
+			continue
+		}
+
+		if f, ok := item["file"].(string); ok {
+			files = append(files, resolvePath(filepath.Dir(filename), f))
 		}
 	}
 
-	return c, w, nil
+	return files, nil
+}
+
+// SceneKey returns a hash of what a scene renders rather than how its file is
+// written: each camera, light and object with its definitions resolved, and
+// the contents of any OBJ files in place of their paths. Comments, unused or
+// reordered definitions and moving a model file leave the key unchanged.
+func SceneKey(filename string) (string, error) {
+	definitions := map[string]interface{}{}
+	dir := filepath.Dir(filename)
+
+	items, err := readScene(filename)
+	if err != nil {
+		return "", err
+	}
+
+	h := sha256.New()
+	for _, item := range items {
+		if _, exists := item["define"]; exists {
+			processDefinition(item, definitions)
+			continue
+		}
+
+		if _, exists := item["add"]; !exists {
+			continue
+		}
+
+		item = addDefinitions(item, definitions)
+		if f, ok := item["file"].(string); ok && item["add"] == "obj" {
+			contents, err := os.ReadFile(resolvePath(dir, f))
+			if err != nil {
+				return "", err
+			}
+			item["file"] = fmt.Sprintf("%x", sha256.Sum256(contents))
+		}
+
+		writeCanonical(h, item)
+		h.Write([]byte{'\n'})
+	}
+
+	return fmt.Sprintf("%x", h.Sum(nil)), nil
+}
+
+// writeCanonical writes v with map keys sorted, so that equal values always
+// give the same output.
+func writeCanonical(w io.Writer, v interface{}) {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(val))
+		for k := range val {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		io.WriteString(w, "{")
+		for _, k := range keys {
+			fmt.Fprintf(w, "%q:", k)
+			writeCanonical(w, val[k])
+			io.WriteString(w, ",")
+		}
+		io.WriteString(w, "}")
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(val))
+		for k, item := range val {
+			m[fmt.Sprint(k)] = item
+		}
+		writeCanonical(w, m)
+	case []interface{}:
+		io.WriteString(w, "[")
+		for _, item := range val {
+			writeCanonical(w, item)
+			io.WriteString(w, ",")
+		}
+		io.WriteString(w, "]")
+	case string:
+		fmt.Fprintf(w, "%q", val)
+	case int:
+		// YAML reads 1 and 1.0 as different types that load the same.
+		fmt.Fprintf(w, "%v", float64(val))
+	default:
+		fmt.Fprintf(w, "%v", val)
+	}
+}
+
+func readScene(filename string) ([]map[string]interface{}, error) {
+	items := []map[string]interface{}{}
+
+	yamlScene, err := os.ReadFile(filename)
+	if err != nil {
+		return items, err
+	}
+
+	err = yaml.Unmarshal(yamlScene, &items)
+	return items, err
+}
+
+func resolvePath(dir, file string) string {
+	if filepath.IsAbs(file) {
+		return file
+	}
+
+	return filepath.Join(dir, file)
 }
 
 func addDefinitions(item map[string]interface{}, definitions map[string]interface{}) map[string]interface{} {
@@ -172,7 +348,7 @@ func processCamera(item map[string]interface{}) *CameraType {
 	return c
 }
 
-func processObject(item map[string]interface{}) shape.Shape {
+func processObject(item map[string]interface{}, dir string) (shape.Shape, error) {
 	var result SceneObject
 
 	err := mapstructure.Decode(item, &result)
@@ -207,7 +383,12 @@ func processObject(item map[string]interface{}) shape.Shape {
 		if c.Closed != nil {
 			obj.(*shape.CylinderType).Closed = *c.Closed
 		}
-
+	case "obj":
+		o, err := shape.LoadObj(resolvePath(dir, result.File))
+		if err != nil {
+			return nil, err
+		}
+		obj = o.GetGroup()
 	}
 
 	mat := material.Material()
@@ -221,7 +402,7 @@ func processObject(item map[string]interface{}) shape.Shape {
 		obj.SetTransform(processTransform(result.Transform))
 	}
 
-	return obj
+	return obj, nil
 }
 
 func processMaterial(mat SceneMaterial) material.MaterialType {
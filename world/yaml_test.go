@@ -0,0 +1,223 @@
+package world
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dannyroes/raytrace/data"
+	"github.com/dannyroes/raytrace/shape"
+)
+
+const testObj = `v -1 1 0
+v -1 0 0
+v 1 0 0
+v 1 1 0
+
+f 1 2 3
+f 1 3 4
+`
+
+const testScene = `- add: camera
+  width: 20
+  height: 10
+  field-of-view: 0.785
+  from: [0, 1.5, -5]
+  to: [0, 1, 0]
+  up: [0, 1, 0]
+
+- add: light
+  at: [-10, 10, -10]
+  intensity: [1, 1, 1]
+
+- add: sphere
+
+- add: obj
+  file: models/square.obj
+  material:
+    colour: [1, 0, 0]
+`
+
+func writeTestScene(t *testing.T) string {
+	dir := t.TempDir()
+
+	err := os.Mkdir(filepath.Join(dir, "models"), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = os.WriteFile(filepath.Join(dir, "models", "square.obj"), []byte(testObj), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	scene := filepath.Join(dir, "scene.yml")
+	err = os.WriteFile(scene, []byte(testScene), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return scene
+}
+
+func TestLoadSceneObj(t *testing.T) {
+	scene := writeTestScene(t)
+
+	c, w, err := LoadScene(scene)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	if c.HSize != 20 || c.VSize != 10 {
+		t.Errorf("Camera size mismatch expected 20x10 received %dx%d", c.HSize, c.VSize)
+	}
+
+	if len(w.Objects) != 2 {
+		t.Fatalf("Object count mismatch expected %d received %d", 2, len(w.Objects))
+	}
+
+	g, ok := w.Objects[1].(*shape.GroupType)
+	if !ok {
+		t.Fatalf("Expected obj to load as a group, received %T", w.Objects[1])
+	}
+
+	triangles := g.Children[0].(*shape.GroupType).Children
+	if len(triangles) != 2 {
+		t.Errorf("Triangle count mismatch expected %d received %d", 2, len(triangles))
+	}
+
+	if triangles[0].GetMaterial().Colour.Red() != 1 || triangles[0].GetMaterial().Colour.Green() != 0 {
+		t.Errorf("Material not applied to obj triangles, received %+v", triangles[0].GetMaterial().Colour)
+	}
+}
+
+func TestLoadSceneMissingObj(t *testing.T) {
+	scene := writeTestScene(t)
+	os.Remove(filepath.Join(filepath.Dir(scene), "models", "square.obj"))
+
+	_, _, err := LoadScene(scene)
+	if err == nil {
+		t.Error("Expected error loading scene with missing obj")
+	}
+}
+
+func TestSceneFiles(t *testing.T) {
+	scene := writeTestScene(t)
+
+	files, err := SceneFiles(scene)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	expected := []string{scene, filepath.Join(filepath.Dir(scene), "models", "square.obj")}
+	if len(files) != len(expected) {
+		t.Fatalf("File count mismatch expected %v received %v", expected, files)
+	}
+
+	for i := range expected {
+		if files[i] != expected[i] {
+			t.Errorf("File mismatch expected %s received %s", expected[i], files[i])
+		}
+	}
+}
+
+func TestSceneMaterials(t *testing.T) {
+	scene := filepath.Join(t.TempDir(), "scene.yml")
+	err := os.WriteFile(scene, []byte(`- define: white-material
+  value:
+    colour: [1, 1, 1]
+    diffuse: 0.7
+    reflective: 0.1
+
+- define: blue-material
+  extend: white-material
+  value:
+    colour: [0.5, 0.8, 0.9]
+
+- define: standard-transform
+  value:
+    - [translate, 1, -1, 1]
+
+- define: not-a-material
+  value:
+    width: 10
+`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	materials, err := SceneMaterials(scene)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	if len(materials) != 2 {
+		t.Fatalf("Material count mismatch expected %d received %+v", 2, materials)
+	}
+
+	if materials[0].Name != "white-material" || materials[1].Name != "blue-material" {
+		t.Errorf("Material names mismatch received %s, %s", materials[0].Name, materials[1].Name)
+	}
+
+	blue := materials[1].Material
+	if !data.FloatEqual(blue.Diffuse, 0.7) || !data.FloatEqual(blue.Reflective, 0.1) {
+		t.Errorf("Expected blue-material to extend white-material, received %+v", blue)
+	}
+
+	if !data.FloatEqual(blue.Colour.Blue(), 0.9) {
+		t.Errorf("Colour mismatch expected %f received %f", 0.9, blue.Colour.Blue())
+	}
+}
+
+func TestSceneKey(t *testing.T) {
+	scene := writeTestScene(t)
+	dir := filepath.Dir(scene)
+
+	key, err := SceneKey(scene)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	write := func(contents string) string {
+		err := os.WriteFile(scene, []byte(contents), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		k, err := SceneKey(scene)
+		if err != nil {
+			t.Fatalf("Unexpected error %v", err)
+		}
+		return k
+	}
+
+	unchanged := write("# a comment\n- define: unused\n  value:\n    diffuse: 0.5\n\n" + testScene)
+	if unchanged != key {
+		t.Error("Expected comments and unused definitions to leave the key unchanged")
+	}
+
+	defined := write(`- define: red
+  value:
+    colour: [1, 0, 0]
+` + strings.Replace(testScene, "material:\n    colour: [1, 0, 0]", "material: red", 1))
+	if defined != key {
+		t.Error("Expected a resolved definition to give the same key as the inline value")
+	}
+
+	changed := write(strings.Replace(testScene, "width: 20", "width: 40", 1))
+	if changed == key {
+		t.Error("Expected changing the camera to change the key")
+	}
+
+	write(testScene)
+	err = os.WriteFile(filepath.Join(dir, "models", "square.obj"), []byte(testObj+"f 2 3 4\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	model, _ := SceneKey(scene)
+	if model == key {
+		t.Error("Expected changing the model to change the key")
+	}
+}